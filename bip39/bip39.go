@@ -0,0 +1,168 @@
+// Package bip39 implements BIP-39 mnemonics, to derive the seed used by EIP-2333 BLS key derivation.
+//
+// Only the English wordlist is supported.
+//
+// BIP-39: https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki
+package bip39
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	blsu "github.com/protolambda/bls12-381-util"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+)
+
+//go:embed english.txt
+var englishTxt string
+
+// English is the BIP-39 English wordlist, 2048 words.
+var English = strings.Split(strings.TrimSpace(englishTxt), "\n")
+
+var englishIndices = func() map[string]uint16 {
+	out := make(map[string]uint16, len(English))
+	for i, w := range English {
+		out[w] = uint16(i)
+	}
+	return out
+}()
+
+const seedIterations = 2048
+
+const seedSize = 64
+
+// NewEntropy returns new random entropy of the given bit size, for use with NewMnemonic.
+// The bit size must be a multiple of 32, in the range [128, 256].
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, fmt.Errorf("%w: %v", blsu.ErrEntropy, err)
+	}
+	return entropy, nil
+}
+
+func checkEntropyBits(bits int) error {
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return fmt.Errorf("entropy must be a multiple of 32 bits in [128, 256], got %d bits", bits)
+	}
+	return nil
+}
+
+// NewMnemonic encodes the entropy as mnemonic sentence, with the checksum appended to the entropy.
+func NewMnemonic(entropy []byte) (string, error) {
+	ent := len(entropy) * 8
+	if err := checkEntropyBits(ent); err != nil {
+		return "", err
+	}
+	// CS = ENT / 32
+	cs := ent / 32
+	h := sha256.Sum256(entropy)
+	// entropy || checksum, the checksum is at most 8 bits, and fits in one extra byte
+	data := make([]byte, len(entropy)+1, len(entropy)+1)
+	copy(data, entropy)
+	data[len(entropy)] = h[0]
+	// MS = (ENT + CS) / 11
+	ms := (ent + cs) / 11
+	words := make([]string, ms, ms)
+	for i := 0; i < ms; i++ {
+		var index uint16
+		for j := 0; j < 11; j++ {
+			bit := i*11 + j
+			index = (index << 1) | uint16((data[bit/8]>>(7-bit%8))&1)
+		}
+		words[i] = English[index]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// GenerateMnemonic generates a new mnemonic sentence from random entropy of the given bit size.
+func GenerateMnemonic(bits int) (string, error) {
+	entropy, err := NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+	return NewMnemonic(entropy)
+}
+
+// MnemonicToEntropy decodes the mnemonic sentence back into entropy, and verifies the checksum.
+// The mnemonic is NFKD normalized, and words are separated by whitespace.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	ms := len(words)
+	if ms%3 != 0 || ms < 12 || ms > 24 {
+		return nil, fmt.Errorf("mnemonic must be 12, 15, 18, 21 or 24 words, got %d", ms)
+	}
+	// ENT + CS = MS * 11, CS = ENT / 32
+	ent := ms * 11 * 32 / 33
+	cs := ent / 32
+	data := make([]byte, ent/8+1, ent/8+1)
+	for i, w := range words {
+		index, ok := englishIndices[w]
+		if !ok {
+			return nil, fmt.Errorf("unknown mnemonic word %d: %q", i, w)
+		}
+		for j := 0; j < 11; j++ {
+			if (index>>(10-j))&1 == 1 {
+				bit := i*11 + j
+				data[bit/8] |= 1 << (7 - bit%8)
+			}
+		}
+	}
+	entropy := data[:ent/8]
+	h := sha256.Sum256(entropy)
+	mask := byte(0xff << (8 - cs))
+	if data[ent/8]&mask != h[0]&mask {
+		return nil, errors.New("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic checks that the mnemonic consists of known words, has a valid length, and a valid checksum.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// MnemonicToSeed validates the mnemonic and derives the 64 byte seed from it, with an optional passphrase.
+// Both the mnemonic and passphrase are NFKD normalized.
+// The seed can be used as input for EIP-2333 blsu.DeriveMasterSK.
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(normalized), []byte(salt), seedIterations, seedSize, sha512.New), nil
+}
+
+// DeriveSK derives the secret key at the given EIP-2334 path, e.g. blsu.SigningKeyPath(i),
+// from the seed of the mnemonic and passphrase.
+func DeriveSK(mnemonic string, passphrase string, path string) (*blsu.SecretKey, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return blsu.DeriveSKFromPath(seed, path)
+}
+
+// ValidatorKeys derives the signing secret key and pubkey of the validator with the given account index,
+// from the seed of the mnemonic and passphrase.
+func ValidatorKeys(mnemonic string, passphrase string, index uint32) (*blsu.SecretKey, *blsu.Pubkey, error) {
+	sk, err := DeriveSK(mnemonic, passphrase, blsu.SigningKeyPath(index))
+	if err != nil {
+		return nil, nil, err
+	}
+	pk, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sk, pk, nil
+}
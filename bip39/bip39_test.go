@@ -0,0 +1,271 @@
+package bip39
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	blsu "github.com/protolambda/bls12-381-util"
+	"testing"
+)
+
+type bip39TestCase struct {
+	Entropy  string
+	Mnemonic string
+	Seed     string // with passphrase "TREZOR"
+}
+
+func TestWordlist(t *testing.T) {
+	if len(English) != 2048 {
+		t.Fatalf("expected 2048 words, got %d", len(English))
+	}
+	// sha256 of https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt
+	h := sha256.Sum256([]byte(englishTxt))
+	if got := hex.EncodeToString(h[:]); got != "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda" {
+		t.Fatalf("unexpected wordlist hash: %s", got)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	for i, tc := range trezorTestVectors {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			entropy, err := hex.DecodeString(tc.Entropy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mnemonic, err := NewMnemonic(entropy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mnemonic != tc.Mnemonic {
+				t.Fatalf("expected mnemonic %q, got %q", tc.Mnemonic, mnemonic)
+			}
+			gotEntropy, err := MnemonicToEntropy(mnemonic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(gotEntropy, entropy) {
+				t.Fatalf("expected entropy %x, got %x", entropy, gotEntropy)
+			}
+			seed, err := MnemonicToSeed(mnemonic, "TREZOR")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(seed); got != tc.Seed {
+				t.Fatalf("expected seed %s, got %s", tc.Seed, got)
+			}
+		})
+	}
+}
+
+func TestInvalidMnemonics(t *testing.T) {
+	for _, m := range invalidMnemonics {
+		if err := ValidateMnemonic(m); err == nil {
+			t.Fatalf("expected invalid mnemonic: %q", m)
+		}
+		if _, err := MnemonicToSeed(m, ""); err == nil {
+			t.Fatalf("expected seed derivation to fail: %q", m)
+		}
+	}
+}
+
+func TestPassphraseNFKD(t *testing.T) {
+	mnemonic := trezorTestVectors[0].Mnemonic
+	// "é" as single composed code point, and as "e" followed by a combining acute accent
+	a, err := MnemonicToSeed(mnemonic, "caf\u00e9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MnemonicToSeed(mnemonic, "cafe\u0301")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatal("expected normalized passphrases to produce the same seed")
+	}
+}
+
+func TestGenerateMnemonic(t *testing.T) {
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		m, err := GenerateMnemonic(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateMnemonic(m); err != nil {
+			t.Fatalf("generated invalid mnemonic %q: %v", m, err)
+		}
+	}
+	for _, bits := range []int{0, 96, 129, 288} {
+		if _, err := GenerateMnemonic(bits); err == nil {
+			t.Fatalf("expected error for %d bits", bits)
+		}
+	}
+}
+
+func TestValidatorKeys(t *testing.T) {
+	tc := trezorTestVectors[0]
+	sk, pk, err := ValidatorKeys(tc.Mnemonic, "TREZOR", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, err := hex.DecodeString(tc.Seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := blsu.DeriveSKFromPath(seed, blsu.SigningKeyPath(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk.Serialize() != expected.Serialize() {
+		t.Fatal("unexpected validator signing key")
+	}
+	expectedPk, err := blsu.SkToPk(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.Serialize() != expectedPk.Serialize() {
+		t.Fatal("unexpected validator pubkey")
+	}
+}
+
+var trezorTestVectors = []bip39TestCase{
+	{
+		Entropy:  "00000000000000000000000000000000",
+		Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		Seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		Entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		Mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		Seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		Entropy:  "80808080808080808080808080808080",
+		Mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		Seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		Entropy:  "ffffffffffffffffffffffffffffffff",
+		Mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		Seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		Entropy:  "000000000000000000000000000000000000000000000000",
+		Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent",
+		Seed:     "035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa",
+	},
+	{
+		Entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		Mnemonic: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will",
+		Seed:     "f2b94508732bcbacbcc020faefecfc89feafa6649a5491b8c952cede496c214a0c7b3c392d168748f2d4a612bada0753b52a1c7ac53c1e93abd5c6320b9e95dd",
+	},
+	{
+		Entropy:  "808080808080808080808080808080808080808080808080",
+		Mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always",
+		Seed:     "107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65",
+	},
+	{
+		Entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffff",
+		Mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo when",
+		Seed:     "0cd6e5d827bb62eb8fc1e262254223817fd068a74b5b449cc2f667c3f1f985a76379b43348d952e2265b4cd129090758b3e3c2c49103b5051aac2eaeb890a528",
+	},
+	{
+		Entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+		Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		Seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+	{
+		Entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		Mnemonic: "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+		Seed:     "bc09fca1804f7e69da93c2f2028eb238c227f2e9dda30cd63699232578480a4021b146ad717fbb7e451ce9eb835f43620bf5c514db0f8add49f5d121449d3e87",
+	},
+	{
+		Entropy:  "8080808080808080808080808080808080808080808080808080808080808080",
+		Mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless",
+		Seed:     "c0c519bd0e91a2ed54357d9d1ebef6f5af218a153624cf4f2da911a0ed8f7a09e2ef61af0aca007096df430022f7a2b6fb91661a9589097069720d015e4e982f",
+	},
+	{
+		Entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		Mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		Seed:     "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+	},
+	{
+		Entropy:  "77c2b00716cec7213839159e404db50d",
+		Mnemonic: "jelly better achieve collect unaware mountain thought cargo oxygen act hood bridge",
+		Seed:     "b5b6d0127db1a9d2226af0c3346031d77af31e918dba64287a1b44b8ebf63cdd52676f672a290aae502472cf2d602c051f3e6f18055e84e4c43897fc4e51a6ff",
+	},
+	{
+		Entropy:  "b63a9c59a6e641f288ebc103017f1da9f8290b3da6bdef7b",
+		Mnemonic: "renew stay biology evidence goat welcome casual join adapt armor shuffle fault little machine walk stumble urge swap",
+		Seed:     "9248d83e06f4cd98debf5b6f010542760df925ce46cf38a1bdb4e4de7d21f5c39366941c69e1bdbf2966e0f6e6dbece898a0e2f0a4c2b3e640953dfe8b7bbdc5",
+	},
+	{
+		Entropy:  "3e141609b97933b66a060dcddc71fad1d91677db872031e85f4c015c5e7e8982",
+		Mnemonic: "dignity pass list indicate nasty swamp pool script soccer toe leaf photo multiply desk host tomato cradle drill spread actor shine dismiss champion exotic",
+		Seed:     "ff7f3184df8696d8bef94b6c03114dbee0ef89ff938712301d27ed8336ca89ef9635da20af07d4175f2bf5f3de130f39c9d9e8dd0472489c19b1a020a940da67",
+	},
+	{
+		Entropy:  "0460ef47585604c5660618db2e6a7e7f",
+		Mnemonic: "afford alter spike radar gate glance object seek swamp infant panel yellow",
+		Seed:     "65f93a9f36b6c85cbe634ffc1f99f2b82cbb10b31edc7f087b4f6cb9e976e9faf76ff41f8f27c99afdf38f7a303ba1136ee48a4c1e7fcd3dba7aa876113a36e4",
+	},
+	{
+		Entropy:  "72f60ebac5dd8add8d2a25a797102c3ce21bc029c200076f",
+		Mnemonic: "indicate race push merry suffer human cruise dwarf pole review arch keep canvas theme poem divorce alter left",
+		Seed:     "3bbf9daa0dfad8229786ace5ddb4e00fa98a044ae4c4975ffd5e094dba9e0bb289349dbe2091761f30f382d4e35c4a670ee8ab50758d2c55881be69e327117ba",
+	},
+	{
+		Entropy:  "2c85efc7f24ee4573d2b81a6ec66cee209b2dcbd09d8eddc51e0215b0b68e416",
+		Mnemonic: "clutch control vehicle tonight unusual clog visa ice plunge glimpse recipe series open hour vintage deposit universe tip job dress radar refuse motion taste",
+		Seed:     "fe908f96f46668b2d5b37d82f558c77ed0d69dd0e7e043a5b0511c48c2f1064694a956f86360c93dd04052a8899497ce9e985ebe0c8c52b955e6ae86d4ff4449",
+	},
+	{
+		Entropy:  "eaebabb2383351fd31d703840b32e9e2",
+		Mnemonic: "turtle front uncle idea crush write shrug there lottery flower risk shell",
+		Seed:     "bdfb76a0759f301b0b899a1e3985227e53b3f51e67e3f2a65363caedf3e32fde42a66c404f18d7b05818c95ef3ca1e5146646856c461c073169467511680876c",
+	},
+	{
+		Entropy:  "7ac45cfe7722ee6c7ba84fbc2d5bd61b45cb2fe5eb65aa78",
+		Mnemonic: "kiss carry display unusual confirm curtain upgrade antique rotate hello void custom frequent obey nut hole price segment",
+		Seed:     "ed56ff6c833c07982eb7119a8f48fd363c4a9b1601cd2de736b01045c5eb8ab4f57b079403485d1c4924f0790dc10a971763337cb9f9c62226f64fff26397c79",
+	},
+	{
+		Entropy:  "4fa1a8bc3e6d80ee1316050e862c1812031493212b7ec3f3bb1b08f168cabeef",
+		Mnemonic: "exile ask congress lamp submit jacket era scheme attend cousin alcohol catch course end lucky hurt sentence oven short ball bird grab wing top",
+		Seed:     "095ee6f817b4c2cb30a5a797360a81a40ab0f9a4e25ecd672a3f58a0b5ba0687c096a6b14d2c0deb3bdefce4f61d01ae07417d502429352e27695163f7447a8c",
+	},
+	{
+		Entropy:  "18ab19a9f54a9274f03e5209a2ac8a91",
+		Mnemonic: "board flee heavy tunnel powder denial science ski answer betray cargo cat",
+		Seed:     "6eff1bb21562918509c73cb990260db07c0ce34ff0e3cc4a8cb3276129fbcb300bddfe005831350efd633909f476c45c88253276d9fd0df6ef48609e8bb7dca8",
+	},
+	{
+		Entropy:  "18a2e1d81b8ecfb2a333adcb0c17a5b9eb76cc5d05db91a4",
+		Mnemonic: "board blade invite damage undo sun mimic interest slam gaze truly inherit resist great inject rocket museum chief",
+		Seed:     "f84521c777a13b61564234bf8f8b62b3afce27fc4062b51bb5e62bdfecb23864ee6ecf07c1d5a97c0834307c5c852d8ceb88e7c97923c0a3b496bedd4e5f88a9",
+	},
+	{
+		Entropy:  "15da872c95a13dd738fbf50e427583ad61f18fd99f628c417a61cf8343c90419",
+		Mnemonic: "beyond stage sleep clip because twist token leaf atom beauty genius food business side grid unable middle armed observe pair crouch tonight away coconut",
+		Seed:     "b15509eaa2d09d3efd3e006ef42151b30367dc6e3aa5e44caba3fe4d3e352e65101fbdb86a96776b91946ff06f8eac594dc6ee1d3e82a42dfe1b40fef6bcc3fd",
+	},
+}
+
+var invalidMnemonics = []string{
+	"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+	"legal winner thank year wave sausage worth useful legal winner thank yellow yellow",
+	"letter advice cage absurd amount doctor acoustic avoid letter advice caged above",
+	"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo, wrong",
+	"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+	"legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will will will",
+	"letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always.",
+	"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo why",
+	"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art art",
+	"legal winner thank year wave sausage worth useful legal winner thanks year wave worth useful legal winner thank year wave sausage worth title",
+	"letter advice cage absurd amount doctor acoustic avoid letters advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless",
+	"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo voted",
+	"jello better achieve collect unaware mountain thought cargo oxygen act hood bridge",
+	"renew, stay, biology, evidence, goat, welcome, casual, join, adapt, armor, shuffle, fault, little, machine, walk, stumble, urge, swap",
+	"dignity pass list indicate nasty",
+	"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon letter",
+}
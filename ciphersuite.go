@@ -0,0 +1,343 @@
+package blsu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	kbls "github.com/kilic/bls12-381"
+	"sort"
+)
+
+// Scheme is the signature scheme of a ciphersuite, each scheme protects against rogue key attacks differently.
+type Scheme uint8
+
+const (
+	// SchemeBasic requires the messages of an aggregate signature to be distinct.
+	SchemeBasic Scheme = iota
+	// SchemeAug prepends the serialized pubkey to each signed message.
+	SchemeAug
+	// SchemePOP requires a proof of possession of each pubkey, see PopProve and PopVerify.
+	SchemePOP
+)
+
+func (s Scheme) String() string {
+	switch s {
+	case SchemeBasic:
+		return "NUL"
+	case SchemeAug:
+		return "AUG"
+	case SchemePOP:
+		return "POP"
+	default:
+		return fmt.Sprintf("Scheme(%d)", uint8(s))
+	}
+}
+
+// suite is the scheme and the DSTs of a ciphersuite, shared by Ciphersuite and CiphersuiteG1,
+// with the checks of the scheme that do not depend on the groups of the pubkeys and signatures.
+type suite struct {
+	scheme Scheme
+	dst    []byte
+	// only used by the POP scheme, for hash_pubkey_to_point
+	popDST []byte
+}
+
+// Ciphersuite is a signature scheme, with the domain separation tag (DST) used to hash messages to G2.
+// Applications can use a custom DST, e.g. to separate signatures of test networks, see NewCiphersuite.
+type Ciphersuite struct {
+	suite
+}
+
+// PopCiphersuite is BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_, the default ciphersuite, used in Eth2.
+var PopCiphersuite = &Ciphersuite{suite{scheme: SchemePOP, dst: domain, popDST: popDomain}}
+
+// AugCiphersuite is BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_AUG_
+var AugCiphersuite = &Ciphersuite{suite{scheme: SchemeAug, dst: augDomain}}
+
+// BasicCiphersuite is BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_
+var BasicCiphersuite = &Ciphersuite{suite{scheme: SchemeBasic, dst: basicDomain}}
+
+func checkDST(dst []byte) error {
+	if len(dst) == 0 {
+		return errors.New("DST may not be empty")
+	}
+	if len(dst) > 255 {
+		return fmt.Errorf("DST may not be longer than 255 bytes, got %d", len(dst))
+	}
+	return nil
+}
+
+// validateCiphersuite checks the DSTs of a ciphersuite with the given scheme, see NewCiphersuite.
+func validateCiphersuite(scheme Scheme, dst []byte, popDST []byte) error {
+	if err := checkDST(dst); err != nil {
+		return err
+	}
+	switch scheme {
+	case SchemeBasic, SchemeAug:
+		if popDST != nil {
+			return fmt.Errorf("%s scheme does not use a POP DST", scheme)
+		}
+	case SchemePOP:
+		if err := checkDST(popDST); err != nil {
+			return fmt.Errorf("invalid POP DST: %v", err)
+		}
+		if bytes.Equal(dst, popDST) {
+			return errors.New("POP DST must be distinct from the DST")
+		}
+	default:
+		return fmt.Errorf("unknown scheme: %s", scheme)
+	}
+	return nil
+}
+
+// newSuite validates the DSTs for the scheme, see NewCiphersuite, and copies them.
+func newSuite(scheme Scheme, dst []byte, popDST []byte) (suite, error) {
+	if err := validateCiphersuite(scheme, dst, popDST); err != nil {
+		return suite{}, err
+	}
+	return suite{
+		scheme: scheme,
+		dst:    append([]byte(nil), dst...),
+		popDST: append([]byte(nil), popDST...),
+	}, nil
+}
+
+// NewCiphersuite creates a ciphersuite with a custom DST.
+// The popDST is only used by the POP scheme, must be distinct from the DST, and must be nil for other schemes.
+// The DSTs must be non-empty, and at most 255 bytes long.
+func NewCiphersuite(scheme Scheme, dst []byte, popDST []byte) (*Ciphersuite, error) {
+	s, err := newSuite(scheme, dst, popDST)
+	if err != nil {
+		return nil, err
+	}
+	return &Ciphersuite{s}, nil
+}
+
+// Scheme returns the signature scheme of the ciphersuite.
+func (s *suite) Scheme() Scheme {
+	return s.scheme
+}
+
+// DST returns a copy of the domain separation tag used to hash messages.
+func (s *suite) DST() []byte {
+	return append([]byte(nil), s.dst...)
+}
+
+// String returns the DST, which is the ciphersuite ID.
+func (s *suite) String() string {
+	return string(s.dst)
+}
+
+// augMessage returns the message as signed in the scheme of the ciphersuite: PK || message for the Aug scheme.
+// The pkRaw function serializes the pubkey, and is only called in the Aug scheme.
+func (s *suite) augMessage(pkRaw func() []byte, message []byte) []byte {
+	if s.scheme != SchemeAug {
+		return message
+	}
+	raw := pkRaw()
+	out := make([]byte, 0, len(raw)+len(message))
+	out = append(out, raw...)
+	return append(out, message...)
+}
+
+// aggregateMessages checks the preconditions of AggregateVerify in the scheme of the ciphersuite,
+// and returns the messages as signed: augMessage(i) is PK_i || message_i in the Aug scheme.
+// The pubkeys and signature are checked by the core aggregate verification.
+func (s *suite) aggregateMessages(pubkeyCount int, messages [][]byte, augMessage func(i int) []byte) ([][]byte, error) {
+	switch s.scheme {
+	case SchemeBasic:
+		// Precondition: n >= 1, otherwise return INVALID.
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("%w: need at least 1 message", ErrEmptyInput)
+		}
+		// 1. If any two input messages are equal, return INVALID.
+		if i := duplicateMessage(messages); i >= 0 {
+			return nil, &IndexError{Index: i, Err: ErrDuplicateMessage}
+		}
+	case SchemeAug:
+		// implicit in spec: pubkeys and messages lengths must be equal
+		if pubkeyCount != len(messages) {
+			return nil, fmt.Errorf("%w: pubkeys: %d, messages: %d", ErrLengthMismatch, pubkeyCount, len(messages))
+		}
+		// 1. for i in 1, ..., n:
+		augMessages := make([][]byte, len(messages), len(messages))
+		for i := range messages {
+			// 2. mprime_i = PK_i || message_i
+			augMessages[i] = augMessage(i)
+		}
+		return augMessages, nil
+	}
+	return messages, nil
+}
+
+// checkFastAggregate returns an error if FastAggregateVerify is not supported by the scheme of the ciphersuite.
+func (s *suite) checkFastAggregate() error {
+	if s.scheme != SchemePOP {
+		return fmt.Errorf("FastAggregateVerify is not supported by the %s scheme", s.scheme)
+	}
+	return nil
+}
+
+// checkPop returns an error if proofs of possession are not supported by the scheme of the ciphersuite.
+func (s *suite) checkPop() error {
+	if s.scheme != SchemePOP {
+		return fmt.Errorf("proof of possession is not supported by the %s scheme", s.scheme)
+	}
+	return nil
+}
+
+// message returns the message as signed in the scheme of the ciphersuite, see augMessage.
+func (cs *Ciphersuite) message(pk *Pubkey, message []byte) []byte {
+	return cs.augMessage(func() []byte {
+		pkRaw := pk.Serialize()
+		return pkRaw[:]
+	}, message)
+}
+
+// The Sign algorithm computes a signature from SK, a secret key, and message, an octet string.
+//
+// Like all signing functions, Sign does not check the secret key: a zero secret key, e.g. a zeroized key,
+// results in the identity signature, which is rejected by all verification functions.
+// Keys from KeyGen, Deserialize and keystores are never zero, use SkToPk to check other keys upfront.
+func (cs *Ciphersuite) Sign(sk *SecretKey, message []byte) *Signature {
+	if cs.scheme == SchemeAug {
+		// 1. PK = SkToPk(SK)
+		// 2. return CoreSign(SK, PK || message)
+		return coreSign(sk, cs.message(skToPk(sk), message), cs.dst)
+	}
+	return coreSign(sk, message, cs.dst)
+}
+
+// The Verify algorithm checks a signature over a message under the public key PK.
+func (cs *Ciphersuite) Verify(pk *Pubkey, message []byte, signature *Signature) bool {
+	return cs.VerifyDetailed(pk, message, signature) == nil
+}
+
+// VerifyDetailed is Verify, but returns why the signature is INVALID, e.g. ErrIdentityPubkey or ErrInvalidSignature.
+func (cs *Ciphersuite) VerifyDetailed(pk *Pubkey, message []byte, signature *Signature) error {
+	return coreVerify(pk, cs.message(pk, message), signature, cs.dst)
+}
+
+// duplicateMessage returns the index of a message that is equal to an earlier message, or -1 if all are distinct.
+func duplicateMessage(messages [][]byte) int {
+	// Sort first, then check if any adjacent messages are equal to spot duplicates
+	order := make([]int, len(messages), len(messages))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(messages[order[i]], messages[order[j]]) < 0
+	})
+	for i, j := 0, 1; j < len(order); i, j = i+1, j+1 {
+		if bytes.Equal(messages[order[i]], messages[order[j]]) {
+			return order[j]
+		}
+	}
+	return -1
+}
+
+// The AggregateVerify algorithm checks an aggregated signature over several (PK, message) pairs.
+//
+// In the Basic scheme all messages must be distinct.
+// In the Aug scheme each message is prefixed with its pubkey.
+func (cs *Ciphersuite) AggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) bool {
+	return cs.AggregateVerifyDetailed(pubkeys, messages, signature) == nil
+}
+
+// AggregateVerifyDetailed is AggregateVerify, but returns why the signature is INVALID.
+// An *IndexError is returned for an invalid pubkey or message.
+func (cs *Ciphersuite) AggregateVerifyDetailed(pubkeys []*Pubkey, messages [][]byte, signature *Signature) error {
+	messages, err := cs.aggregateMessages(len(pubkeys), messages, func(i int) []byte {
+		return cs.message(pubkeys[i], messages[i])
+	})
+	if err != nil {
+		return err
+	}
+	// return coreAggregateVerify((PK_1, ..., PK_n), (message_1, ..., message_n), signature)
+	return coreAggregateVerify(pubkeys, messages, signature, cs.dst)
+}
+
+// FastAggregateVerify is a verification algorithm for the aggregate of multiple signatures on the same message.
+// This function is faster than AggregateVerify.
+//
+// This function applies only to the Proof Of Possession signature scheme, and returns INVALID for other schemes.
+func (cs *Ciphersuite) FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) bool {
+	return cs.FastAggregateVerifyDetailed(pubkeys, message, signature) == nil
+}
+
+// FastAggregateVerifyDetailed is FastAggregateVerify, but returns why the signature is INVALID.
+// An *IndexError is returned for an invalid pubkey.
+func (cs *Ciphersuite) FastAggregateVerifyDetailed(pubkeys []*Pubkey, message []byte, signature *Signature) error {
+	if err := cs.checkFastAggregate(); err != nil {
+		return err
+	}
+	// 1. aggregate = pubkey_to_point(PK_1)
+	// 2. for i in 2, ..., n:
+	// 3. next = pubkey_to_point(PK_i)
+	// 4. aggregate = aggregate + next
+	// 5. PK = point_to_pubkey(aggregate)
+	PK, err := AggregatePubkeys(pubkeys)
+	if err != nil {
+		return err
+	}
+	// 6. return coreVerify(PK, message, signature)
+	return coreVerify(PK, message, signature, cs.dst)
+}
+
+// Eth2FastAggregateVerify wraps FastAggregateVerify, accepting the G2_POINT_AT_INFINITY signature when pubkeys is empty.
+func (cs *Ciphersuite) Eth2FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) bool {
+	// if len(pubkeys) == 0 and signature == G2_POINT_AT_INFINITY: return True
+
+	// G2_POINT_AT_INFINITY(serialized form is b'\xc0' + b'\x00' * 95, i.e. top 2 bits are one.
+	// most significant bit: to indicate it's compressed (if it were serialized)
+	// second most signficant bit: to indicate it is at infinity, the rest of the bits must be zero then.
+
+	// The IsZero method does not actually use the G2 scratchpad,
+	// so we call into this method with a nil-receiver to avoid unnecessary work.
+	if len(pubkeys) == 0 && (*kbls.G2)(nil).IsZero((*kbls.PointG2)(signature)) {
+		return true
+	}
+	return cs.FastAggregateVerify(pubkeys, message, signature)
+}
+
+// The PopProve algorithm generates a proof of possession for the public key corresponding to secret key SK.
+//
+// This function applies only to the Proof Of Possession signature scheme, and returns an error for other schemes.
+func (cs *Ciphersuite) PopProve(sk *SecretKey) (*Signature, error) {
+	if err := cs.checkPop(); err != nil {
+		return nil, err
+	}
+	// 1. PK = SkToPk(SK)
+	// a zero secret key is not checked, and results in the identity proof, see Sign
+	pk := skToPk(sk)
+	// 2. Q = hash_pubkey_to_point(PK)
+	// 3. R = SK * Q
+	// 4. proof = point_to_signature(R)
+	pkRaw := pk.Serialize()
+	// 5. return proof
+	return coreSign(sk, pkRaw[:], cs.popDST), nil
+}
+
+// The PopVerify algorithm checks whether proof is valid for PK.
+//
+// This function applies only to the Proof Of Possession signature scheme, and returns INVALID for other schemes.
+func (cs *Ciphersuite) PopVerify(pk *Pubkey, proof *Signature) bool {
+	if cs.checkPop() != nil {
+		return false
+	}
+	// 1. R = signature_to_point(proof)
+	// 2. If R is INVALID, return INVALID
+	// part of the Signature deserialization
+	// 3. If KeyValidate(PK) is INVALID, return INVALID
+	// part of the Pubkey deserialization, except the identity pubkey check
+	if (*kbls.G1)(nil).IsZero((*kbls.PointG1)(pk)) {
+		return false
+	}
+	// 4. xP = pubkey_to_point(PK)
+	// 5. Q = hash_pubkey_to_point(PK)
+	// 6. C1 = pairing(R, P)
+	// 7. C2 = pairing(Q, xP)
+	// 8. If C1 == C2, return VALID, else return INVALID
+	pkRaw := pk.Serialize()
+	return coreVerify(pk, pkRaw[:], proof, cs.popDST) == nil
+}
@@ -0,0 +1,223 @@
+package blsu
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewCiphersuite(t *testing.T) {
+	longDST := bytes.Repeat([]byte{'a'}, 256)
+	cases := []struct {
+		name   string
+		scheme Scheme
+		dst    []byte
+		popDST []byte
+		valid  bool
+	}{
+		{"basic", SchemeBasic, []byte("TESTNET_NUL_"), nil, true},
+		{"aug", SchemeAug, []byte("TESTNET_AUG_"), nil, true},
+		{"pop", SchemePOP, []byte("TESTNET_SIG_POP_"), []byte("TESTNET_POP_POP_"), true},
+		{"max length", SchemeBasic, longDST[:255], nil, true},
+		{"empty dst", SchemeBasic, nil, nil, false},
+		{"too long dst", SchemeBasic, longDST, nil, false},
+		{"basic with pop dst", SchemeBasic, []byte("TESTNET_NUL_"), []byte("TESTNET_POP_"), false},
+		{"pop without pop dst", SchemePOP, []byte("TESTNET_SIG_POP_"), nil, false},
+		{"pop with too long pop dst", SchemePOP, []byte("TESTNET_SIG_POP_"), longDST, false},
+		{"pop with equal dsts", SchemePOP, []byte("TESTNET_POP_"), []byte("TESTNET_POP_"), false},
+		{"unknown scheme", Scheme(42), []byte("TESTNET_"), nil, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cs, err := NewCiphersuite(c.scheme, c.dst, c.popDST)
+			if !c.valid {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cs.Scheme() != c.scheme {
+				t.Fatalf("unexpected scheme: %s", cs.Scheme())
+			}
+			if !bytes.Equal(cs.DST(), c.dst) {
+				t.Fatalf("unexpected DST: %q", cs.DST())
+			}
+		})
+	}
+}
+
+func TestCiphersuiteDefaults(t *testing.T) {
+	sk := randSK(t)
+	pk, err := SkToPk(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hello ciphersuite")
+	if !Verify(pk, msg, PopCiphersuite.Sign(sk, msg)) {
+		t.Fatal("expected PopCiphersuite to match the package-level functions")
+	}
+	if !BasicVerify(pk, msg, BasicCiphersuite.Sign(sk, msg)) {
+		t.Fatal("expected BasicCiphersuite to match the Basic scheme functions")
+	}
+	if !AugVerify(pk, msg, AugCiphersuite.Sign(sk, msg)) {
+		t.Fatal("expected AugCiphersuite to match the Aug scheme functions")
+	}
+}
+
+func TestCiphersuiteCustomDST(t *testing.T) {
+	for _, scheme := range []Scheme{SchemeBasic, SchemeAug, SchemePOP} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			var popDST []byte
+			if scheme == SchemePOP {
+				popDST = []byte("TESTNET_POP_")
+			}
+			cs, err := NewCiphersuite(scheme, []byte("TESTNET_SIG_"), popDST)
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := NewCiphersuite(scheme, []byte("OTHERNET_SIG_"), popDST)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := 4
+			pubkeys := make([]*Pubkey, n, n)
+			messages := make([][]byte, n, n)
+			signatures := make([]*Signature, n, n)
+			for i := 0; i < n; i++ {
+				sk := randSK(t)
+				pubkeys[i], err = SkToPk(sk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				messages[i] = []byte{byte(i), 0xaa}
+				signatures[i] = cs.Sign(sk, messages[i])
+				if !cs.Verify(pubkeys[i], messages[i], signatures[i]) {
+					t.Fatalf("expected valid signature %d", i)
+				}
+				if other.Verify(pubkeys[i], messages[i], signatures[i]) {
+					t.Fatalf("signature %d must not be valid with another DST", i)
+				}
+			}
+			aggSig, err := Aggregate(signatures)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cs.AggregateVerify(pubkeys, messages, aggSig) {
+				t.Fatal("expected valid aggregate signature")
+			}
+			if other.AggregateVerify(pubkeys, messages, aggSig) {
+				t.Fatal("aggregate signature must not be valid with another DST")
+			}
+			if valid, err := cs.SignatureSetVerify(pubkeys, messages, signatures); err != nil || !valid {
+				t.Fatalf("expected valid signature set: %v", err)
+			}
+			if valid, err := other.SignatureSetVerify(pubkeys, messages, signatures); err != nil || valid {
+				t.Fatalf("signature set must not be valid with another DST: %v", err)
+			}
+			check := cs.NewAggregateCheck()
+			if err := check.AggregateVerify(pubkeys, messages, aggSig); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < n; i++ {
+				if err := check.Verify(pubkeys[i], messages[i], signatures[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := check.Check(); err != nil {
+				t.Fatal(err)
+			}
+			check = other.NewAggregateCheck()
+			if err := check.Verify(pubkeys[0], messages[0], signatures[0]); err != nil {
+				t.Fatal(err)
+			}
+			if err := check.Check(); err == nil {
+				t.Fatal("deferred check must not be valid with another DST")
+			}
+		})
+	}
+}
+
+func TestCiphersuitePop(t *testing.T) {
+	cs, err := NewCiphersuite(SchemePOP, []byte("TESTNET_SIG_"), []byte("TESTNET_POP_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 3
+	sks := make([]*SecretKey, n, n)
+	pubkeys := make([]*Pubkey, n, n)
+	signatures := make([]*Signature, n, n)
+	msg := []byte("same message")
+	for i := 0; i < n; i++ {
+		sks[i] = randSK(t)
+		pubkeys[i], err = SkToPk(sks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := cs.PopProve(sks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cs.PopVerify(pubkeys[i], proof) {
+			t.Fatalf("expected valid proof %d", i)
+		}
+		if PopVerify(pubkeys[i], proof) {
+			t.Fatalf("proof %d must not be valid with the default POP DST", i)
+		}
+		signatures[i] = cs.Sign(sks[i], msg)
+	}
+	aggSig, err := Aggregate(signatures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cs.FastAggregateVerify(pubkeys, msg, aggSig) {
+		t.Fatal("expected valid fast aggregate signature")
+	}
+	if FastAggregateVerify(pubkeys, msg, aggSig) {
+		t.Fatal("fast aggregate signature must not be valid with the default DST")
+	}
+	check := cs.NewAggregateCheck()
+	if err := check.FastAggregateVerify(pubkeys, msg, aggSig); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the POP functions are not available in other schemes
+	if _, err := BasicCiphersuite.PopProve(sks[0]); err == nil {
+		t.Fatal("expected PopProve to fail in the Basic scheme")
+	}
+	if BasicCiphersuite.FastAggregateVerify(pubkeys, msg, BasicCiphersuite.Sign(sks[0], msg)) {
+		t.Fatal("expected FastAggregateVerify to fail in the Basic scheme")
+	}
+	if err := AugCiphersuite.NewAggregateCheck().FastAggregateVerify(pubkeys, msg, aggSig); err == nil {
+		t.Fatal("expected deferred FastAggregateVerify to fail in the Aug scheme")
+	}
+}
+
+func TestCiphersuiteBasicDistinctMessages(t *testing.T) {
+	sk1, sk2 := randSK(t), randSK(t)
+	pk1, err := SkToPk(sk1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk2, err := SkToPk(sk2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("duplicate")
+	aggSig, err := Aggregate([]*Signature{BasicCiphersuite.Sign(sk1, msg), BasicCiphersuite.Sign(sk2, msg)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkeys := []*Pubkey{pk1, pk2}
+	messages := [][]byte{msg, msg}
+	if BasicCiphersuite.AggregateVerify(pubkeys, messages, aggSig) {
+		t.Fatal("expected duplicate messages to be rejected")
+	}
+	if err := BasicCiphersuite.NewAggregateCheck().AggregateVerify(pubkeys, messages, aggSig); err == nil {
+		t.Fatal("expected deferred duplicate messages to be rejected")
+	}
+}
@@ -0,0 +1,210 @@
+package blsu
+
+import (
+	kbls "github.com/kilic/bls12-381"
+	"math/bits"
+	"unsafe"
+)
+
+// Constant-time scalar multiplication in G1 and G2, used for signing.
+//
+// The kilic G2.MulScalar uses a variable-time GLV and wNAF multiplication,
+// which can leak bits of the secret scalar through timing.
+// Instead, the scalar is recoded into a fixed number of odd, non-zero, signed window digits
+// (Joye and Tunstall, "Exponent Recoding and Regular Exponentiation Algorithms"),
+// which are processed with a fixed sequence of doublings and additions,
+// and with constant-time table lookups and conditional negations.
+//
+// All table entries are affine, and every digit is non-zero,
+// so the kilic point addition takes the same mixed-addition code path for every digit.
+
+// window size, in bits
+const ctWindow = 4
+
+// number of odd multiples in the table: 1P, 3P, ..., (2**ctWindow - 1)P
+const ctTableSize = 1 << (ctWindow - 1)
+
+// number of signed digits, enough for any scalar below 2**255
+const ctDigits = 64
+
+// number of 64 bit words in a kilic G1 point: 3 coordinates, of 6 words each
+const g1Words = 3 * 6
+
+// number of 64 bit words in a kilic G2 point: 3 coordinates, of 2 field elements, of 6 words each
+const g2Words = 3 * 2 * 6
+
+// compile-time checks of the point layouts assumed by ctSelectG1 and ctSelectG2
+var _ [unsafe.Sizeof(kbls.PointG1{}) - g1Words*8]struct{}
+var _ [g1Words*8 - unsafe.Sizeof(kbls.PointG1{})]struct{}
+var _ [unsafe.Sizeof(kbls.PointG2{}) - g2Words*8]struct{}
+var _ [g2Words*8 - unsafe.Sizeof(kbls.PointG2{})]struct{}
+
+// the order r of the BLS12-381 subgroups, as little-endian 64 bit words
+var frModulus = [4]uint64{0xffffffff00000001, 0x53bda402fffe5bfe, 0x3339d80809a1d805, 0x73eda753299d7d48}
+
+// ctEqMask returns all ones if a == b, and zero otherwise. a and b must be smaller than 2**63.
+func ctEqMask(a, b uint64) uint64 {
+	return -(((a ^ b) - 1) >> 63)
+}
+
+// ctSelectG1 sets r to a if the mask is all ones, or to b if the mask is zero.
+func ctSelectG1(r, a, b *kbls.PointG1, mask uint64) {
+	rw := (*[g1Words]uint64)(unsafe.Pointer(r))
+	aw := (*[g1Words]uint64)(unsafe.Pointer(a))
+	bw := (*[g1Words]uint64)(unsafe.Pointer(b))
+	for i := 0; i < g1Words; i++ {
+		rw[i] = (aw[i] & mask) | (bw[i] &^ mask)
+	}
+}
+
+// ctLookupG1 sets r to table[index], reading every table entry.
+func ctLookupG1(r *kbls.PointG1, table *[ctTableSize]kbls.PointG1, index uint64) {
+	rw := (*[g1Words]uint64)(unsafe.Pointer(r))
+	*rw = [g1Words]uint64{}
+	for j := 0; j < ctTableSize; j++ {
+		mask := ctEqMask(uint64(j), index)
+		tw := (*[g1Words]uint64)(unsafe.Pointer(&table[j]))
+		for i := 0; i < g1Words; i++ {
+			rw[i] |= tw[i] & mask
+		}
+	}
+}
+
+// ctSelectG2 sets r to a if the mask is all ones, or to b if the mask is zero.
+func ctSelectG2(r, a, b *kbls.PointG2, mask uint64) {
+	rw := (*[g2Words]uint64)(unsafe.Pointer(r))
+	aw := (*[g2Words]uint64)(unsafe.Pointer(a))
+	bw := (*[g2Words]uint64)(unsafe.Pointer(b))
+	for i := 0; i < g2Words; i++ {
+		rw[i] = (aw[i] & mask) | (bw[i] &^ mask)
+	}
+}
+
+// ctLookupG2 sets r to table[index], reading every table entry.
+func ctLookupG2(r *kbls.PointG2, table *[ctTableSize]kbls.PointG2, index uint64) {
+	rw := (*[g2Words]uint64)(unsafe.Pointer(r))
+	*rw = [g2Words]uint64{}
+	for j := 0; j < ctTableSize; j++ {
+		mask := ctEqMask(uint64(j), index)
+		tw := (*[g2Words]uint64)(unsafe.Pointer(&table[j]))
+		for i := 0; i < g2Words; i++ {
+			rw[i] |= tw[i] & mask
+		}
+	}
+}
+
+// ctRecode recodes the scalar into odd signed digits, least significant first,
+// such that scalar = sum(digits[i] * 2**(ctWindow*i)), with digits in [-(2**ctWindow - 1), 2**ctWindow - 1].
+// Even scalars are first replaced by r - scalar, which is odd. The returned mask is all ones in that case,
+// to negate the result of the multiplication.
+func ctRecode(digits *[ctDigits]int64, scalar *kbls.Fr) (negMask uint64) {
+	k := [4]uint64(*scalar)
+	// negation modulo r, to turn even scalars into odd ones
+	var kNeg [4]uint64
+	var borrow uint64
+	kNeg[0], borrow = bits.Sub64(frModulus[0], k[0], 0)
+	kNeg[1], borrow = bits.Sub64(frModulus[1], k[1], borrow)
+	kNeg[2], borrow = bits.Sub64(frModulus[2], k[2], borrow)
+	kNeg[3], _ = bits.Sub64(frModulus[3], k[3], borrow)
+	negMask = (k[0] & 1) - 1
+	for i := 0; i < 4; i++ {
+		k[i] = (kNeg[i] & negMask) | (k[i] &^ negMask)
+	}
+	for i := 0; i < ctDigits-1; i++ {
+		// d = (k mod 2**(w+1)) - 2**w, odd and non-zero since k is odd
+		digits[i] = int64(k[0]&(1<<(ctWindow+1)-1)) - (1 << ctWindow)
+		// k = (k - d) / 2**w, which is equal to 2*(k >> (w+1)) + 1, and odd again
+		k[0] = (k[0] >> ctWindow) | (k[1] << (64 - ctWindow))
+		k[1] = (k[1] >> ctWindow) | (k[2] << (64 - ctWindow))
+		k[2] = (k[2] >> ctWindow) | (k[3] << (64 - ctWindow))
+		k[3] = k[3] >> ctWindow
+		k[0] |= 1
+	}
+	// the remainder is the last, positive, digit
+	digits[ctDigits-1] = int64(k[0])
+	k = [4]uint64{}
+	kNeg = [4]uint64{}
+	return negMask
+}
+
+// ctMulG2 sets r to scalar * p, in constant time with respect to the scalar.
+func ctMulG2(g2 *kbls.G2, r *kbls.PointG2, p *kbls.PointG2, scalar *kbls.Fr) *kbls.PointG2 {
+	// table of odd multiples: table[j] = (2j + 1) * p
+	var table [ctTableSize]kbls.PointG2
+	var double kbls.PointG2
+	table[0].Set(p)
+	g2.Double(&double, p)
+	for j := 1; j < ctTableSize; j++ {
+		g2.Add(&table[j], &table[j-1], &double)
+	}
+	tablePtrs := make([]*kbls.PointG2, ctTableSize, ctTableSize)
+	for j := range table {
+		tablePtrs[j] = &table[j]
+	}
+	g2.AffineBatch(tablePtrs)
+
+	var digits [ctDigits]int64
+	negMask := ctRecode(&digits, scalar)
+
+	var acc, sel, neg kbls.PointG2
+	// the last digit is always positive
+	ctLookupG2(&acc, &table, uint64(digits[ctDigits-1])>>1)
+	for i := ctDigits - 2; i >= 0; i-- {
+		for j := 0; j < ctWindow; j++ {
+			g2.Double(&acc, &acc)
+		}
+		d := uint64(digits[i])
+		sign := uint64(digits[i] >> 63)
+		abs := (d ^ sign) - sign
+		// abs is odd, (abs - 1) / 2 is the table index
+		ctLookupG2(&sel, &table, abs>>1)
+		g2.Neg(&neg, &sel)
+		ctSelectG2(&sel, &neg, &sel, sign)
+		g2.Add(&acc, &acc, &sel)
+	}
+	g2.Neg(&neg, &acc)
+	ctSelectG2(r, &neg, &acc, negMask)
+	digits = [ctDigits]int64{}
+	return r
+}
+
+// ctMulG1 sets r to scalar * p, in constant time with respect to the scalar. See ctMulG2.
+func ctMulG1(g1 *kbls.G1, r *kbls.PointG1, p *kbls.PointG1, scalar *kbls.Fr) *kbls.PointG1 {
+	// table of odd multiples: table[j] = (2j + 1) * p
+	var table [ctTableSize]kbls.PointG1
+	var double kbls.PointG1
+	table[0].Set(p)
+	g1.Double(&double, p)
+	for j := 1; j < ctTableSize; j++ {
+		g1.Add(&table[j], &table[j-1], &double)
+	}
+	tablePtrs := make([]*kbls.PointG1, ctTableSize, ctTableSize)
+	for j := range table {
+		tablePtrs[j] = &table[j]
+	}
+	g1.AffineBatch(tablePtrs)
+
+	var digits [ctDigits]int64
+	negMask := ctRecode(&digits, scalar)
+
+	var acc, sel, neg kbls.PointG1
+	// the last digit is always positive
+	ctLookupG1(&acc, &table, uint64(digits[ctDigits-1])>>1)
+	for i := ctDigits - 2; i >= 0; i-- {
+		for j := 0; j < ctWindow; j++ {
+			g1.Double(&acc, &acc)
+		}
+		d := uint64(digits[i])
+		sign := uint64(digits[i] >> 63)
+		abs := (d ^ sign) - sign
+		// abs is odd, (abs - 1) / 2 is the table index
+		ctLookupG1(&sel, &table, abs>>1)
+		g1.Neg(&neg, &sel)
+		ctSelectG1(&sel, &neg, &sel, sign)
+		g1.Add(&acc, &acc, &sel)
+	}
+	g1.Neg(&neg, &acc)
+	ctSelectG1(r, &neg, &acc, negMask)
+	digits = [ctDigits]int64{}
+	return r
+}
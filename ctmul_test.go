@@ -0,0 +1,189 @@
+package blsu
+
+import (
+	"crypto/rand"
+	kbls "github.com/kilic/bls12-381"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCtMulG2(t *testing.T) {
+	g2 := kbls.NewG2()
+	Q, err := g2.HashToCurve([]byte("ctMulG2"), domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(big.Int).SetBytes(kbls.NewG2().Q().Bytes())
+	var scalars []*kbls.Fr
+	for _, v := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(3),
+		big.NewInt(16),
+		big.NewInt(17),
+		new(big.Int).Sub(r, big.NewInt(1)),
+		new(big.Int).Sub(r, big.NewInt(2)),
+		new(big.Int).Lsh(big.NewInt(1), 254),
+	} {
+		var s kbls.Fr
+		s.FromBytes(v.Bytes())
+		scalars = append(scalars, &s)
+	}
+	for i := 0; i < 50; i++ {
+		var s kbls.Fr
+		if _, err := s.Rand(rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+		scalars = append(scalars, &s)
+	}
+	for i, s := range scalars {
+		var expected, got kbls.PointG2
+		g2.MulScalar(&expected, Q, s)
+		ctMulG2(g2, &got, Q, s)
+		if !g2.Equal(&expected, &got) {
+			t.Fatalf("scalar %d (%x): constant-time multiplication differs", i, s.ToBytes())
+		}
+	}
+}
+
+func TestCtMulG1(t *testing.T) {
+	g1 := kbls.NewG1()
+	Q, err := g1.HashToCurve([]byte("ctMulG1"), minSigDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(big.Int).SetBytes(kbls.NewG1().Q().Bytes())
+	var scalars []*kbls.Fr
+	for _, v := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(r, big.NewInt(1)),
+		new(big.Int).Sub(r, big.NewInt(2)),
+	} {
+		var s kbls.Fr
+		s.FromBytes(v.Bytes())
+		scalars = append(scalars, &s)
+	}
+	for i := 0; i < 50; i++ {
+		var s kbls.Fr
+		if _, err := s.Rand(rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+		scalars = append(scalars, &s)
+	}
+	for i, s := range scalars {
+		var expected, got kbls.PointG1
+		g1.MulScalar(&expected, Q, s)
+		ctMulG1(g1, &got, Q, s)
+		if !g1.Equal(&expected, &got) {
+			t.Fatalf("scalar %d (%x): constant-time multiplication differs", i, s.ToBytes())
+		}
+	}
+}
+
+// welchT computes Welch's t-statistic of the two samples.
+func welchT(a, b []float64) float64 {
+	meanVar := func(x []float64) (float64, float64) {
+		var sum float64
+		for _, v := range x {
+			sum += v
+		}
+		mean := sum / float64(len(x))
+		var sq float64
+		for _, v := range x {
+			sq += (v - mean) * (v - mean)
+		}
+		return mean, sq / float64(len(x)-1)
+	}
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	return (ma - mb) / math.Sqrt(va/float64(len(a))+vb/float64(len(b)))
+}
+
+// cropped drops the slowest samples, which are mostly caused by scheduling and GC noise.
+func cropped(x []float64, keep float64) []float64 {
+	sorted := append([]float64(nil), x...)
+	sort.Float64s(sorted)
+	return sorted[:int(float64(len(sorted))*keep)]
+}
+
+// checkTimingLeakage is a dudect-style leakage test of mul:
+// it compares timings of fixed scalar classes against random scalars, interleaved in random order,
+// and fails if Welch's t-test detects a difference. The fixed classes are chosen with the group order q.
+func checkTimingLeakage(t *testing.T, q *big.Int, mul func(s *kbls.Fr)) {
+	fixedClasses := map[string]*kbls.Fr{
+		"one":  new(kbls.Fr).One(),
+		"two":  new(kbls.Fr).FromBytes([]byte{2}),
+		"high": new(kbls.Fr).FromBytes(new(big.Int).Sub(q, big.NewInt(1)).Bytes()),
+	}
+	const samples = 300
+	for name, fixed := range fixedClasses {
+		t.Run(name, func(t *testing.T) {
+			var fixedTimes, randomTimes []float64
+			var coin [1]byte
+			for len(fixedTimes) < samples || len(randomTimes) < samples {
+				if _, err := rand.Read(coin[:]); err != nil {
+					t.Fatal(err)
+				}
+				useFixed := coin[0]&1 == 0
+				s := fixed
+				if !useFixed {
+					s = new(kbls.Fr)
+					if _, err := s.Rand(rand.Reader); err != nil {
+						t.Fatal(err)
+					}
+				}
+				start := time.Now()
+				mul(s)
+				d := float64(time.Since(start).Nanoseconds())
+				if useFixed {
+					fixedTimes = append(fixedTimes, d)
+				} else {
+					randomTimes = append(randomTimes, d)
+				}
+			}
+			tStat := welchT(cropped(fixedTimes, 0.9), cropped(randomTimes, 0.9))
+			t.Logf("t-statistic: %.2f", tStat)
+			// dudect considers |t| > 10 as definite leakage, the threshold is loose to tolerate noisy machines
+			if math.Abs(tStat) > 10 {
+				t.Fatalf("timing depends on the scalar, t = %.2f", tStat)
+			}
+		})
+	}
+}
+
+// TestCtMulG1Timing checks ctMulG1 of the generator, as used by skToPk, for timing leakage, see checkTimingLeakage.
+// Timings are noisy on shared machines, the test only runs with BLSU_TIMING_TESTS=1.
+func TestCtMulG1Timing(t *testing.T) {
+	if os.Getenv("BLSU_TIMING_TESTS") != "1" {
+		t.Skip("timing test skipped, set BLSU_TIMING_TESTS=1 to run it")
+	}
+	g1 := kbls.NewG1()
+	var out kbls.PointG1
+	checkTimingLeakage(t, g1.Q(), func(s *kbls.Fr) {
+		ctMulG1(g1, &out, &kbls.G1One, s)
+	})
+}
+
+// TestCtMulG2Timing checks ctMulG2 of a hashed message, as used by signing, for timing leakage, see checkTimingLeakage.
+// Timings are noisy on shared machines, the test only runs with BLSU_TIMING_TESTS=1.
+func TestCtMulG2Timing(t *testing.T) {
+	if os.Getenv("BLSU_TIMING_TESTS") != "1" {
+		t.Skip("timing test skipped, set BLSU_TIMING_TESTS=1 to run it")
+	}
+	g2 := kbls.NewG2()
+	Q, err := g2.HashToCurve([]byte("ctMulG2 timing"), domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out kbls.PointG2
+	checkTimingLeakage(t, g2.Q(), func(s *kbls.Fr) {
+		ctMulG2(g2, &out, Q, s)
+	})
+}
@@ -0,0 +1,90 @@
+package blsu
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// EIP-2333, BLS12-381 key generation:
+// https://eips.ethereum.org/EIPS/eip-2333
+//
+
+// lamportChunks is the number of 32 byte chunks in a lamport secret key
+const lamportChunks = 255
+
+// ikmToLamportPK derives the lamport secret key chunks, and hashes each of them into the lamport public key.
+// The hashes are written into the given lamport public key buffer.
+func ikmToLamportPK(lamportPK []byte, ikm []byte, salt []byte) {
+	// 1. OKM = HKDF(salt, IKM, b'', L)
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	var okm [lamportChunks * 32]byte
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, nil), okm[:]); err != nil {
+		// only when the output is too long, which we know it is not
+		panic(err)
+	}
+	// 2. lamport_SK = [OKM[i:i+K] for i in range(0, L, K)]
+	// parent_SK_to_lamport_PK: lamport_PK = lamport_PK | SHA256(lamport_SK[i])
+	for i := 0; i < lamportChunks; i++ {
+		h := sha256.Sum256(okm[i*32 : (i+1)*32])
+		copy(lamportPK[i*32:(i+1)*32], h[:])
+	}
+	// don't leave the lamport secret key around
+	zeroBytes(okm[:])
+	zeroBytes(prk)
+}
+
+// parentSKToLamportPK computes the compressed lamport public key of the parent secret key, for the given child index.
+func parentSKToLamportPK(parent *SecretKey, index uint32) [32]byte {
+	// 0. salt = I2OSP(index, 4)
+	var salt [4]byte
+	binary.BigEndian.PutUint32(salt[:], index)
+	// 1. IKM = I2OSP(parent_SK, 32)
+	ikm := parent.Serialize()
+	// 3. not_IKM = flip_bits(IKM)
+	var notIKM [32]byte
+	for i := range ikm {
+		notIKM[i] = ^ikm[i]
+	}
+	var lamportPK [2 * lamportChunks * 32]byte
+	// 2. lamport_0 = IKM_to_lamport_SK(IKM, salt)
+	// 5. for i in 1, .., 255: lamport_PK = lamport_PK | SHA256(lamport_0[i])
+	ikmToLamportPK(lamportPK[:lamportChunks*32], ikm[:], salt[:])
+	// 4. lamport_1 = IKM_to_lamport_SK(not_IKM, salt)
+	// 6. for i in 1, .., 255: lamport_PK = lamport_PK | SHA256(lamport_1[i])
+	ikmToLamportPK(lamportPK[lamportChunks*32:], notIKM[:], salt[:])
+	zeroBytes(ikm[:])
+	zeroBytes(notIKM[:])
+	// 7. compressed_lamport_PK = SHA256(lamport_PK)
+	// 8. return compressed_lamport_PK
+	return sha256.Sum256(lamportPK[:])
+}
+
+// DeriveMasterSK derives the EIP-2333 master secret key from a seed.
+// The seed MUST be at least 32 bytes long.
+func DeriveMasterSK(seed []byte) (*SecretKey, error) {
+	// 0. if len(seed) < 32: raise ValueError
+	if len(seed) < 32 {
+		return nil, errors.New("seed must be at least 32 bytes")
+	}
+	// 1. SK = HKDF_mod_r(seed)
+	// 2. return SK
+	return hkdfModR(seed, nil)
+}
+
+// DeriveChildSK derives the EIP-2333 child secret key at the given index from the parent secret key.
+func DeriveChildSK(parent *SecretKey, index uint32) *SecretKey {
+	// 0. compressed_lamport_PK = parent_SK_to_lamport_PK(parent_SK, index)
+	compressedLamportPK := parentSKToLamportPK(parent, index)
+	defer zeroBytes(compressedLamportPK[:])
+	// 1. SK = HKDF_mod_r(compressed_lamport_PK)
+	sk, err := hkdfModR(compressedLamportPK[:], nil)
+	if err != nil {
+		// only when the output is too long, which we know it is not
+		panic(err)
+	}
+	// 2. return SK
+	return sk
+}
@@ -0,0 +1,70 @@
+package blsu
+
+import (
+	"encoding/hex"
+	"fmt"
+	kbls "github.com/kilic/bls12-381"
+	"testing"
+)
+
+type eip2333TestCase struct {
+	Seed       string
+	MasterSK   string // decimal
+	ChildIndex uint32
+	ChildSK    string // decimal
+}
+
+var eip2333TestCases = []eip2333TestCase{
+	{
+		Seed:       "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		MasterSK:   "6083874454709270928345386274498605044986640685124978867557563392430687146096",
+		ChildIndex: 0,
+		ChildSK:    "20397789859736650942317412262472558107875392172444076792671091975210932703118",
+	},
+	{
+		Seed:       "3141592653589793238462643383279502884197169399375105820974944592",
+		MasterSK:   "29757020647961307431480504535336562678282505419141012933316116377660817309383",
+		ChildIndex: 3141592653,
+		ChildSK:    "25457201688850691947727629385191704516744796114925897962676248250929345014287",
+	},
+	{
+		Seed:       "0099ff991111002299dd7744ee3355bbdd8844115566cc55663355668888cc00",
+		MasterSK:   "27580842291869792442942448775674722299803720648445448686099262467207037398656",
+		ChildIndex: 4294967295,
+		ChildSK:    "29358610794459428860402234341874281240803786294062035874021252734817515685787",
+	},
+	{
+		Seed:       "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		MasterSK:   "19022158461524446591288038168518313374041767046816487870552872741050760015818",
+		ChildIndex: 42,
+		ChildSK:    "31372231650479070279774297061823572166496564838472787488249775572789064611981",
+	},
+}
+
+func TestEIP2333(t *testing.T) {
+	for i, tc := range eip2333TestCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			seed, err := hex.DecodeString(tc.Seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			master, err := DeriveMasterSK(seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := (*kbls.Fr)(master).ToBig().String(); got != tc.MasterSK {
+				t.Fatalf("expected master %s, got %s", tc.MasterSK, got)
+			}
+			child := DeriveChildSK(master, tc.ChildIndex)
+			if got := (*kbls.Fr)(child).ToBig().String(); got != tc.ChildSK {
+				t.Fatalf("expected child %s, got %s", tc.ChildSK, got)
+			}
+		})
+	}
+}
+
+func TestDeriveMasterSKShortSeed(t *testing.T) {
+	if _, err := DeriveMasterSK(make([]byte, 31)); err == nil {
+		t.Fatal("expected error for seed shorter than 32 bytes")
+	}
+}
@@ -0,0 +1,81 @@
+package blsu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EIP-2334, BLS12-381 deterministic account hierarchy:
+// https://eips.ethereum.org/EIPS/eip-2334
+//
+// m / purpose / coin_type / account / use
+
+// PathPurpose is the purpose level of an EIP-2334 path, the first level after the master node.
+const PathPurpose uint32 = 12381
+
+// PathCoinTypeEth2 is the coin_type level of an EIP-2334 path for Eth2 keys.
+const PathCoinTypeEth2 uint32 = 3600
+
+// WithdrawalKeyPath returns the EIP-2334 path of the withdrawal key of the validator with the given account index.
+func WithdrawalKeyPath(index uint32) string {
+	return fmt.Sprintf("m/%d/%d/%d/0", PathPurpose, PathCoinTypeEth2, index)
+}
+
+// SigningKeyPath returns the EIP-2334 path of the signing key of the validator with the given account index.
+func SigningKeyPath(index uint32) string {
+	return fmt.Sprintf("m/%d/%d/%d/0/0", PathPurpose, PathCoinTypeEth2, index)
+}
+
+// ParseDerivationPath parses an EIP-2334 path, e.g. "m/12381/3600/0/0/0", into the child indices after the master node.
+// The path must contain at least the purpose, coin_type, account and use levels,
+// and the purpose and coin_type must match PathPurpose and PathCoinTypeEth2.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("path must start with master node \"m\": %q", path)
+	}
+	parts = parts[1:]
+	if len(parts) < 4 {
+		return nil, fmt.Errorf("path must contain purpose, coin_type, account and use levels: %q", path)
+	}
+	indices := make([]uint32, len(parts), len(parts))
+	for i, p := range parts {
+		// ParseUint accepts some prefixes and signs we do not want, only plain decimal numbers are valid.
+		if len(p) == 0 || strings.TrimLeft(p, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid path level %d: %q", i+1, p)
+		}
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path level %d: %v", i+1, err)
+		}
+		indices[i] = uint32(v)
+	}
+	if indices[0] != PathPurpose {
+		return nil, fmt.Errorf("path purpose must be %d, got %d", PathPurpose, indices[0])
+	}
+	if indices[1] != PathCoinTypeEth2 {
+		return nil, fmt.Errorf("path coin_type must be %d, got %d", PathCoinTypeEth2, indices[1])
+	}
+	return indices, nil
+}
+
+// DeriveSKFromPath derives the EIP-2333 master secret key from the seed,
+// and then derives the child secret keys along the given EIP-2334 path.
+func DeriveSKFromPath(seed []byte, path string) (*SecretKey, error) {
+	indices, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := DeriveMasterSK(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indices {
+		child := DeriveChildSK(sk, index)
+		// intermediate keys are not returned, wipe them
+		sk.Zeroize()
+		sk = child
+	}
+	return sk, nil
+}
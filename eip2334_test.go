@@ -0,0 +1,82 @@
+package blsu
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseDerivationPath(t *testing.T) {
+	valid := map[string][]uint32{
+		"m/12381/3600/0/0":            {12381, 3600, 0, 0},
+		"m/12381/3600/0/0/0":          {12381, 3600, 0, 0, 0},
+		"m/12381/3600/4294967295/0/0": {12381, 3600, 4294967295, 0, 0},
+	}
+	for path, expected := range valid {
+		got, err := ParseDerivationPath(path)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", path, err)
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Fatalf("expected %v, got %v", expected, got)
+			}
+		}
+	}
+	invalid := []string{
+		"",
+		"m",
+		"m/12381/3600/0",
+		"/12381/3600/0/0",
+		"n/12381/3600/0/0",
+		"m/12381/3600/0/0/",
+		"m/12381/3600//0",
+		"m/12381/3600/0'/0",
+		"m/12381/3600/+1/0",
+		"m/12381/3600/-1/0",
+		"m/12381/3600/0x1/0",
+		"m/12381/3600/4294967296/0",
+		"m/44/3600/0/0",
+		"m/12381/60/0/0",
+	}
+	for _, path := range invalid {
+		if _, err := ParseDerivationPath(path); err == nil {
+			t.Fatalf("expected error for path %q", path)
+		}
+	}
+}
+
+func TestDeriveSKFromPath(t *testing.T) {
+	seed, err := hex.DecodeString(eip2333TestCases[0].Seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DeriveSKFromPath(seed, SigningKeyPath(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := DeriveMasterSK(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []uint32{12381, 3600, 7, 0, 0} {
+		sk = DeriveChildSK(sk, index)
+	}
+	if got.Serialize() != sk.Serialize() {
+		t.Fatal("path derivation does not match manual child derivation")
+	}
+	if _, err := DeriveSKFromPath(seed, "m/12381/3600/0"); err == nil {
+		t.Fatal("expected path error")
+	}
+}
+
+func TestKeyPaths(t *testing.T) {
+	if p := WithdrawalKeyPath(3); p != "m/12381/3600/3/0" {
+		t.Fatalf("unexpected withdrawal path: %s", p)
+	}
+	if p := SigningKeyPath(3); p != "m/12381/3600/3/0/0" {
+		t.Fatalf("unexpected signing path: %s", p)
+	}
+}
@@ -0,0 +1,254 @@
+package blsu
+
+import (
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Standard Go encodings of the key types, following the Ethereum JSON API conventions:
+// text and JSON use 0x-prefixed hex of the compressed point or secret key bytes,
+// binary uses the raw bytes, see Serialize.
+// The marshal methods have value receivers, to also encode values, e.g. fields of a struct, instead of the raw limbs.
+
+var (
+	_ encoding.TextMarshaler     = Pubkey{}
+	_ encoding.TextUnmarshaler   = (*Pubkey)(nil)
+	_ encoding.BinaryMarshaler   = Pubkey{}
+	_ encoding.BinaryUnmarshaler = (*Pubkey)(nil)
+	_ json.Marshaler             = Pubkey{}
+	_ json.Unmarshaler           = (*Pubkey)(nil)
+
+	_ encoding.TextMarshaler     = Signature{}
+	_ encoding.TextUnmarshaler   = (*Signature)(nil)
+	_ encoding.BinaryMarshaler   = Signature{}
+	_ encoding.BinaryUnmarshaler = (*Signature)(nil)
+	_ json.Marshaler             = Signature{}
+	_ json.Unmarshaler           = (*Signature)(nil)
+
+	_ encoding.TextMarshaler     = SecretKey{}
+	_ encoding.TextUnmarshaler   = (*SecretKey)(nil)
+	_ encoding.BinaryMarshaler   = SecretKey{}
+	_ encoding.BinaryUnmarshaler = (*SecretKey)(nil)
+	_ json.Marshaler             = SecretKey{}
+	_ json.Unmarshaler           = (*SecretKey)(nil)
+	_ fmt.Formatter              = SecretKey{}
+)
+
+// encodeHex encodes the bytes as 0x-prefixed hex text.
+func encodeHex(b []byte) []byte {
+	out := make([]byte, 2+hex.EncodedLen(len(b)))
+	copy(out, "0x")
+	hex.Encode(out[2:], b)
+	return out
+}
+
+// decodeHex decodes 0x-prefixed hex text into dst, the text must encode exactly len(dst) bytes.
+func decodeHex(dst []byte, text []byte) error {
+	if len(text) < 2 || text[0] != '0' || (text[1] != 'x' && text[1] != 'X') {
+		return errors.New("hex string must have 0x prefix")
+	}
+	text = text[2:]
+	if len(text) != hex.EncodedLen(len(dst)) {
+		return fmt.Errorf("hex string must encode %d bytes, got %d characters", len(dst), len(text))
+	}
+	_, err := hex.Decode(dst, text)
+	return err
+}
+
+// quote wraps the text in JSON string quotes. Hex text never needs escaping.
+func quote(text []byte) []byte {
+	out := make([]byte, 0, len(text)+2)
+	out = append(out, '"')
+	out = append(out, text...)
+	return append(out, '"')
+}
+
+// isNull returns true if the JSON value is null, which unmarshals as a no-op, by the encoding/json convention.
+func isNull(data []byte) bool {
+	return string(data) == "null"
+}
+
+// unquote gets the contents of a JSON string.
+func unquote(data []byte) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+func (pub Pubkey) MarshalText() ([]byte, error) {
+	out := pub.Serialize()
+	return encodeHex(out[:]), nil
+}
+
+// UnmarshalText decodes 0x-prefixed hex, and deserializes it like Deserialize.
+func (pub *Pubkey) UnmarshalText(text []byte) error {
+	var raw [48]byte
+	if err := decodeHex(raw[:], text); err != nil {
+		return fmt.Errorf("invalid pubkey hex: %v", err)
+	}
+	return pub.Deserialize(&raw)
+}
+
+func (pub Pubkey) MarshalBinary() ([]byte, error) {
+	out := pub.Serialize()
+	return out[:], nil
+}
+
+// UnmarshalBinary deserializes the 48 bytes like Deserialize.
+func (pub *Pubkey) UnmarshalBinary(data []byte) error {
+	if len(data) != 48 {
+		return fmt.Errorf("pubkey must be 48 bytes, got %d", len(data))
+	}
+	return pub.Deserialize((*[48]byte)(data))
+}
+
+func (pub Pubkey) MarshalJSON() ([]byte, error) {
+	text, _ := pub.MarshalText()
+	return quote(text), nil
+}
+
+func (pub *Pubkey) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	text, err := unquote(data)
+	if err != nil {
+		return err
+	}
+	return pub.UnmarshalText(text)
+}
+
+// String returns the 0x-prefixed hex of the compressed pubkey.
+func (pub Pubkey) String() string {
+	text, _ := pub.MarshalText()
+	return string(text)
+}
+
+func (sig Signature) MarshalText() ([]byte, error) {
+	out := sig.Serialize()
+	return encodeHex(out[:]), nil
+}
+
+// UnmarshalText decodes 0x-prefixed hex, and deserializes it like Deserialize.
+func (sig *Signature) UnmarshalText(text []byte) error {
+	var raw [96]byte
+	if err := decodeHex(raw[:], text); err != nil {
+		return fmt.Errorf("invalid signature hex: %v", err)
+	}
+	return sig.Deserialize(&raw)
+}
+
+func (sig Signature) MarshalBinary() ([]byte, error) {
+	out := sig.Serialize()
+	return out[:], nil
+}
+
+// UnmarshalBinary deserializes the 96 bytes like Deserialize.
+func (sig *Signature) UnmarshalBinary(data []byte) error {
+	if len(data) != 96 {
+		return fmt.Errorf("signature must be 96 bytes, got %d", len(data))
+	}
+	return sig.Deserialize((*[96]byte)(data))
+}
+
+func (sig Signature) MarshalJSON() ([]byte, error) {
+	text, _ := sig.MarshalText()
+	return quote(text), nil
+}
+
+func (sig *Signature) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	text, err := unquote(data)
+	if err != nil {
+		return err
+	}
+	return sig.UnmarshalText(text)
+}
+
+// String returns the 0x-prefixed hex of the compressed signature.
+func (sig Signature) String() string {
+	text, _ := sig.MarshalText()
+	return string(text)
+}
+
+// MarshalText encodes the secret key as 0x-prefixed hex.
+// Note that this exposes the secret key, unlike String and the fmt verbs.
+func (sk SecretKey) MarshalText() ([]byte, error) {
+	defer sk.Zeroize()
+	raw := sk.Serialize()
+	defer zeroBytes(raw[:])
+	return encodeHex(raw[:]), nil
+}
+
+// UnmarshalText decodes 0x-prefixed hex, and deserializes it like Deserialize.
+func (sk *SecretKey) UnmarshalText(text []byte) error {
+	var raw [32]byte
+	defer zeroBytes(raw[:])
+	if err := decodeHex(raw[:], text); err != nil {
+		// the hex error may contain secret characters, do not include it
+		return errors.New("invalid secret key hex")
+	}
+	return sk.Deserialize(&raw)
+}
+
+// MarshalBinary encodes the secret key as 32 big-endian bytes.
+// Note that this exposes the secret key, unlike String and the fmt verbs.
+func (sk SecretKey) MarshalBinary() ([]byte, error) {
+	defer sk.Zeroize()
+	raw := sk.Serialize()
+	defer zeroBytes(raw[:])
+	return append([]byte(nil), raw[:]...), nil
+}
+
+// UnmarshalBinary deserializes the 32 bytes like Deserialize.
+func (sk *SecretKey) UnmarshalBinary(data []byte) error {
+	if len(data) != 32 {
+		return fmt.Errorf("secret key must be 32 bytes, got %d", len(data))
+	}
+	return sk.Deserialize((*[32]byte)(data))
+}
+
+// MarshalJSON encodes the secret key as 0x-prefixed hex JSON string.
+// Note that this exposes the secret key, unlike String and the fmt verbs.
+func (sk SecretKey) MarshalJSON() ([]byte, error) {
+	defer sk.Zeroize()
+	text, _ := sk.MarshalText()
+	defer zeroBytes(text)
+	return quote(text), nil
+}
+
+func (sk *SecretKey) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	text, err := unquote(data)
+	if err != nil {
+		return errors.New("invalid secret key JSON")
+	}
+	defer zeroBytes(text)
+	return sk.UnmarshalText(text)
+}
+
+const redactedSecretKey = "SecretKey(redacted)"
+
+// String redacts the secret key, to not leak it into logs by accident. Use MarshalText to encode the key.
+func (sk SecretKey) String() string {
+	return redactedSecretKey
+}
+
+// GoString redacts the secret key, for the %#v verb.
+func (sk SecretKey) GoString() string {
+	return redactedSecretKey
+}
+
+// Format redacts the secret key for all fmt verbs, including %x and %d.
+func (sk SecretKey) Format(f fmt.State, verb rune) {
+	_, _ = f.Write([]byte(redactedSecretKey))
+}
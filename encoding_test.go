@@ -0,0 +1,232 @@
+package blsu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPubkeyEncoding(t *testing.T) {
+	pubkeys, _, _ := prepareSignatureSetTest(t, 1)
+	pub := pubkeys[0]
+	raw := pub.Serialize()
+	expectedText := fmt.Sprintf("0x%x", raw[:])
+
+	text, err := pub.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != expectedText {
+		t.Fatalf("unexpected text: %s", text)
+	}
+	if pub.String() != expectedText {
+		t.Fatalf("unexpected string: %s", pub)
+	}
+	var decoded Pubkey
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Serialize() != raw {
+		t.Fatal("text roundtrip failed")
+	}
+
+	bin, err := pub.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bin, raw[:]) {
+		t.Fatal("unexpected binary")
+	}
+	var decodedBin Pubkey
+	if err := decodedBin.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if decodedBin.Serialize() != raw {
+		t.Fatal("binary roundtrip failed")
+	}
+	if err := decodedBin.UnmarshalBinary(bin[:47]); err == nil {
+		t.Fatal("expected short binary to fail")
+	}
+
+	type container struct {
+		Pubkey *Pubkey `json:"pubkey"`
+	}
+	data, err := json.Marshal(&container{Pubkey: pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"pubkey":"`+expectedText+`"}` {
+		t.Fatalf("unexpected JSON: %s", data)
+	}
+	var c container
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Pubkey.Serialize() != raw {
+		t.Fatal("JSON roundtrip failed")
+	}
+
+	for _, invalid := range []string{
+		strings.TrimPrefix(expectedText, "0x"),
+		expectedText[:len(expectedText)-2],
+		expectedText + "00",
+		"0x" + strings.Repeat("zz", 48),
+		"0x" + strings.Repeat("00", 48),
+	} {
+		if err := decoded.UnmarshalText([]byte(invalid)); err == nil {
+			t.Fatalf("expected invalid text to fail: %s", invalid)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"pubkey":123}`), &c); err == nil {
+		t.Fatal("expected non-string JSON to fail")
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	_, _, signatures := prepareSignatureSetTest(t, 1)
+	sig := signatures[0]
+	raw := sig.Serialize()
+	expectedText := fmt.Sprintf("0x%x", raw[:])
+
+	data, err := json.Marshal(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"`+expectedText+`"` {
+		t.Fatalf("unexpected JSON: %s", data)
+	}
+	var decoded Signature
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Serialize() != raw {
+		t.Fatal("JSON roundtrip failed")
+	}
+	if sig.String() != expectedText {
+		t.Fatalf("unexpected string: %s", sig)
+	}
+	bin, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decodedBin Signature
+	if err := decodedBin.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if decodedBin.Serialize() != raw {
+		t.Fatal("binary roundtrip failed")
+	}
+	if err := decoded.UnmarshalText([]byte(expectedText[:len(expectedText)-2])); err == nil {
+		t.Fatal("expected short text to fail")
+	}
+}
+
+func TestSecretKeyEncoding(t *testing.T) {
+	sk := randSK(t)
+	raw := sk.Serialize()
+	expectedText := fmt.Sprintf("0x%x", raw[:])
+
+	data, err := json.Marshal(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"`+expectedText+`"` {
+		t.Fatalf("unexpected JSON: %s", data)
+	}
+	var decoded SecretKey
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Serialize() != raw {
+		t.Fatal("JSON roundtrip failed")
+	}
+	bin, err := sk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decodedBin SecretKey
+	if err := decodedBin.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if decodedBin.Serialize() != raw {
+		t.Fatal("binary roundtrip failed")
+	}
+	if err := decoded.UnmarshalText([]byte("0x" + strings.Repeat("00", 32))); err == nil {
+		t.Fatal("expected zero secret key to fail")
+	}
+
+	// the secret key must not show up in formatted output
+	secretHex := strings.TrimPrefix(expectedText, "0x")
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%x", "%X", "%d", "%q"} {
+		for _, v := range []interface{}{sk, *sk} {
+			out := fmt.Sprintf(verb, v)
+			if out != redactedSecretKey {
+				t.Fatalf("expected %s to redact the secret key, got: %s", verb, out)
+			}
+		}
+	}
+	type container struct {
+		Key *SecretKey
+	}
+	if out := fmt.Sprintf("%+v", container{sk}); strings.Contains(out, secretHex) || !strings.Contains(out, redactedSecretKey) {
+		t.Fatalf("expected nested secret key to be redacted, got: %s", out)
+	}
+}
+
+func TestValueFieldEncoding(t *testing.T) {
+	pubkeys, _, signatures := prepareSignatureSetTest(t, 1)
+	sk := randSK(t)
+	type container struct {
+		P Pubkey
+		S Signature
+		K SecretKey
+	}
+	in := container{P: *pubkeys[0], S: *signatures[0], K: *sk}
+	pubRaw := in.P.Serialize()
+	sigRaw := in.S.Serialize()
+	skRaw := sk.Serialize()
+	expected := fmt.Sprintf(`{"P":"0x%x","S":"0x%x","K":"0x%x"}`, pubRaw[:], sigRaw[:], skRaw[:])
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Fatalf("unexpected JSON of value fields: %s", data)
+	}
+	if in.K != *sk {
+		t.Fatal("expected marshaling to not zeroize the secret key field")
+	}
+	var out container
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.P.Serialize() != pubRaw || out.S.Serialize() != sigRaw || out.K.Serialize() != skRaw {
+		t.Fatal("JSON roundtrip of value fields failed")
+	}
+	// null is a no-op, like for other encoding/json Unmarshalers
+	if err := json.Unmarshal([]byte(`{"P":null,"S":null,"K":null}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.P.Serialize() != pubRaw || out.S.Serialize() != sigRaw || out.K.Serialize() != skRaw {
+		t.Fatal("expected null to not change the value fields")
+	}
+	if err := out.P.UnmarshalJSON([]byte("null")); err != nil || out.P.Serialize() != pubRaw {
+		t.Fatalf("expected null to be a no-op for the pubkey, got: %v", err)
+	}
+	if err := out.S.UnmarshalJSON([]byte("null")); err != nil || out.S.Serialize() != sigRaw {
+		t.Fatalf("expected null to be a no-op for the signature, got: %v", err)
+	}
+	if err := out.K.UnmarshalJSON([]byte("null")); err != nil || out.K.Serialize() != skRaw {
+		t.Fatalf("expected null to be a no-op for the secret key, got: %v", err)
+	}
+	// values format as hex, like pointers
+	if got := fmt.Sprintf("%v", in.P); got != fmt.Sprintf("0x%x", pubRaw[:]) {
+		t.Fatalf("unexpected pubkey value format: %s", got)
+	}
+	if got := fmt.Sprintf("%v", in.S); got != fmt.Sprintf("0x%x", sigRaw[:]) {
+		t.Fatalf("unexpected signature value format: %s", got)
+	}
+}
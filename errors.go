@@ -0,0 +1,36 @@
+package blsu
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the verification and aggregation functions, wrapped with details.
+// Use errors.Is to check for them, and errors.As with *IndexError to get the index of the failing input.
+var (
+	ErrIdentityPubkey    = errors.New("pubkey is the identity point")
+	ErrIdentitySignature = errors.New("signature is the identity point")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrLengthMismatch    = errors.New("input length mismatch")
+	ErrEmptyInput        = errors.New("empty input")
+	ErrDuplicateMessage  = errors.New("duplicate message")
+	ErrEntropy           = errors.New("failed to read entropy")
+)
+
+// errIdentitySignature is returned for the identity signature, by Verify and by signature sets alike:
+// it is an ErrIdentitySignature, and also an ErrInvalidSignature.
+var errIdentitySignature = fmt.Errorf("%w: %w", ErrInvalidSignature, ErrIdentitySignature)
+
+// IndexError is an error of the input at Index, e.g. an identity pubkey in an aggregate.
+type IndexError struct {
+	Index int
+	Err   error
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("input %d: %v", e.Index, e.Err)
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
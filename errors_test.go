@@ -0,0 +1,154 @@
+package blsu
+
+import (
+	"errors"
+	"testing"
+)
+
+func expectIndexError(t *testing.T, err error, index int, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected %q, got: %v", target, err)
+	}
+	var indexErr *IndexError
+	if !errors.As(err, &indexErr) {
+		t.Fatalf("expected index error, got: %v", err)
+	}
+	if indexErr.Index != index {
+		t.Fatalf("expected index %d, got %d", index, indexErr.Index)
+	}
+}
+
+func TestDetailedErrors(t *testing.T) {
+	pubkeys, messages, signatures := prepareSignatureSetTest(t, 3)
+	var identity Pubkey
+	identityRaw := [48]byte{0xc0}
+	if err := identity.Deserialize(&identityRaw); err != nil {
+		t.Fatal(err)
+	}
+	aggSig, err := Aggregate(signatures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyDetailed(pubkeys[0], messages[0], signatures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDetailed(pubkeys[0], messages[1], signatures[0]); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got: %v", err)
+	}
+	if err := VerifyDetailed(&identity, messages[0], signatures[0]); !errors.Is(err, ErrIdentityPubkey) {
+		t.Fatalf("expected identity pubkey, got: %v", err)
+	}
+
+	if err := AggregateVerifyDetailed(pubkeys, messages, aggSig); err != nil {
+		t.Fatal(err)
+	}
+	if err := AggregateVerifyDetailed(pubkeys, messages, signatures[0]); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got: %v", err)
+	}
+	if err := AggregateVerifyDetailed(pubkeys[:2], messages, aggSig); !errors.Is(err, ErrLengthMismatch) {
+		t.Fatalf("expected length mismatch, got: %v", err)
+	}
+	if err := AggregateVerifyDetailed(nil, nil, aggSig); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected empty input, got: %v", err)
+	}
+	withIdentity := []*Pubkey{pubkeys[0], pubkeys[1], &identity}
+	expectIndexError(t, AggregateVerifyDetailed(withIdentity, messages, aggSig), 2, ErrIdentityPubkey)
+
+	duplicates := [][]byte{messages[0], messages[1], messages[0]}
+	expectIndexError(t, BasicCiphersuite.AggregateVerifyDetailed(pubkeys, duplicates, aggSig), 2, ErrDuplicateMessage)
+
+	if err := FastAggregateVerifyDetailed(nil, messages[0], aggSig); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected empty input, got: %v", err)
+	}
+	expectIndexError(t, FastAggregateVerifyDetailed(withIdentity, messages[0], aggSig), 2, ErrIdentityPubkey)
+	if err := FastAggregateVerifyDetailed(pubkeys, messages[0], aggSig); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got: %v", err)
+	}
+
+	if _, err := Aggregate(nil); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected empty input, got: %v", err)
+	}
+	_, err = AggregatePubkeys(withIdentity)
+	expectIndexError(t, err, 2, ErrIdentityPubkey)
+	if _, err := SignatureSetVerify(pubkeys, messages[:2], signatures); !errors.Is(err, ErrLengthMismatch) {
+		t.Fatalf("expected length mismatch, got: %v", err)
+	}
+}
+
+func TestDeferBLSErrors(t *testing.T) {
+	pubkeys, messages, signatures := prepareSignatureSetTest(t, 3)
+	var identity Pubkey
+	identityRaw := [48]byte{0xc0}
+	if err := identity.Deserialize(&identityRaw); err != nil {
+		t.Fatal(err)
+	}
+	withIdentity := []*Pubkey{pubkeys[0], &identity, pubkeys[2]}
+	var identitySig Signature
+	identitySigRaw := [96]byte{0xc0}
+	if err := identitySig.Deserialize(&identitySigRaw); err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range deferBLSTypes {
+		t.Run(typ.name, func(t *testing.T) {
+			check := typ.create()
+			expectIdentitySignature(t, "Verify", check.Verify(pubkeys[0], messages[0], &identitySig))
+			expectIdentitySignature(t, "AggregateVerify", check.AggregateVerify(pubkeys, messages, &identitySig))
+			expectIndexError(t, check.AggregateVerify(withIdentity, messages, signatures[0]), 1, ErrIdentityPubkey)
+			expectIndexError(t, check.FastAggregateVerify(withIdentity, messages[0], signatures[0]), 1, ErrIdentityPubkey)
+			if err := check.AggregateVerify(pubkeys, messages[:1], signatures[0]); !errors.Is(err, ErrLengthMismatch) {
+				t.Fatalf("expected length mismatch, got: %v", err)
+			}
+			if err := check.FastAggregateVerify(nil, messages[0], signatures[0]); !errors.Is(err, ErrEmptyInput) {
+				t.Fatalf("expected empty input, got: %v", err)
+			}
+			err := check.Verify(pubkeys[0], messages[1], signatures[0])
+			if err == nil {
+				err = check.Check()
+			}
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("expected invalid signature, got: %v", err)
+			}
+		})
+	}
+}
+
+// expectIdentitySignature checks that err is both an ErrIdentitySignature and an ErrInvalidSignature.
+func expectIdentitySignature(t *testing.T, name string, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrIdentitySignature) || !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected %s to return an identity signature error, got: %v", name, err)
+	}
+}
+
+func TestIdentitySignatureErrors(t *testing.T) {
+	pubkeys, messages, signatures := prepareSignatureSetTest(t, 3)
+	var identitySig Signature
+	identitySigRaw := [96]byte{0xc0}
+	if err := identitySig.Deserialize(&identitySigRaw); err != nil {
+		t.Fatal(err)
+	}
+	expectIdentitySignature(t, "VerifyDetailed", VerifyDetailed(pubkeys[0], messages[0], &identitySig))
+	expectIdentitySignature(t, "AggregateVerifyDetailed", AggregateVerifyDetailed(pubkeys, messages, &identitySig))
+	expectIdentitySignature(t, "FastAggregateVerifyDetailed", FastAggregateVerifyDetailed(pubkeys, messages[0], &identitySig))
+	// signature sets reject the identity signature without an error, it is not an operational error
+	withIdentity := []*Signature{signatures[0], &identitySig, signatures[2]}
+	if valid, err := SignatureSetVerify(pubkeys, messages, withIdentity); valid || err != nil {
+		t.Fatalf("expected SignatureSetVerify to be invalid without error, got: %v, %v", valid, err)
+	}
+
+	_, pubsG2, msgsG1, sigsG1 := prepareMinSigTest(t, PopCiphersuiteG1, 3)
+	var identitySigG1 SignatureG1
+	identitySigG1Raw := [48]byte{0xc0}
+	if err := identitySigG1.Deserialize(&identitySigG1Raw); err != nil {
+		t.Fatal(err)
+	}
+	expectIdentitySignature(t, "VerifyDetailedG1", VerifyDetailedG1(pubsG2[0], msgsG1[0], &identitySigG1))
+	expectIdentitySignature(t, "AggregateVerifyDetailedG1", AggregateVerifyDetailedG1(pubsG2, msgsG1, &identitySigG1))
+	expectIdentitySignature(t, "FastAggregateVerifyDetailedG1", FastAggregateVerifyDetailedG1(pubsG2, msgsG1[0], &identitySigG1))
+	withIdentityG1 := []*SignatureG1{sigsG1[0], &identitySigG1, sigsG1[2]}
+	if valid, err := SignatureSetVerifyG1(pubsG2, msgsG1, withIdentityG1); valid || err != nil {
+		t.Fatalf("expected SignatureSetVerifyG1 to be invalid without error, got: %v, %v", valid, err)
+	}
+}
@@ -0,0 +1,120 @@
+package eth2
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+// Gwei is an amount of ETH, in units of 10^-9 ETH.
+type Gwei uint64
+
+// DepositMessage is the message signed by the deposit signature, the proof-of-possession of the pubkey.
+// The pubkey is kept as raw bytes: deposits with invalid pubkeys are valid deposit contract inputs,
+// and only fail in VerifyDepositData.
+type DepositMessage struct {
+	Pubkey                [48]byte
+	WithdrawalCredentials Root
+	Amount                Gwei
+}
+
+// HashTreeRoot returns the SSZ hash_tree_root of the DepositMessage container.
+func (m *DepositMessage) HashTreeRoot() ([32]byte, error) {
+	var chunks [4 * 32]byte
+	pubkeyRoot := blsu.PubkeyRoot(&m.Pubkey)
+	copy(chunks[0:32], pubkeyRoot[:])
+	copy(chunks[32:64], m.WithdrawalCredentials[:])
+	binary.LittleEndian.PutUint64(chunks[64:72], uint64(m.Amount))
+	return merkleize4(&chunks), nil
+}
+
+// DepositData is the deposit contract input: the DepositMessage with its signature.
+type DepositData struct {
+	Pubkey                [48]byte
+	WithdrawalCredentials Root
+	Amount                Gwei
+	Signature             [96]byte
+}
+
+// Message returns the DepositMessage that is signed by the deposit signature.
+func (d *DepositData) Message() *DepositMessage {
+	return &DepositMessage{
+		Pubkey:                d.Pubkey,
+		WithdrawalCredentials: d.WithdrawalCredentials,
+		Amount:                d.Amount,
+	}
+}
+
+// HashTreeRoot returns the SSZ hash_tree_root of the DepositData container, the deposit_data_root.
+func (d *DepositData) HashTreeRoot() ([32]byte, error) {
+	var chunks [4 * 32]byte
+	pubkeyRoot := blsu.PubkeyRoot(&d.Pubkey)
+	copy(chunks[0:32], pubkeyRoot[:])
+	copy(chunks[32:64], d.WithdrawalCredentials[:])
+	binary.LittleEndian.PutUint64(chunks[64:72], uint64(d.Amount))
+	signatureRoot := blsu.SignatureRoot(&d.Signature)
+	copy(chunks[96:128], signatureRoot[:])
+	return merkleize4(&chunks), nil
+}
+
+// merkleize4 returns the merkle root of 4 chunks.
+func merkleize4(chunks *[4 * 32]byte) [32]byte {
+	left := sha256.Sum256(chunks[:64])
+	right := sha256.Sum256(chunks[64:])
+	var pair [64]byte
+	copy(pair[:32], left[:])
+	copy(pair[32:], right[:])
+	return sha256.Sum256(pair[:])
+}
+
+// ComputeDepositDomain returns the deposit domain.
+// Deposits are valid across forks: the domain uses the genesis fork version, and a zero genesis validators root.
+func ComputeDepositDomain(genesisForkVersion Version) Domain {
+	return ComputeDomain(DomainDeposit, genesisForkVersion, Root{})
+}
+
+// SignDepositData creates the deposit data for the secret key, signed with the deposit domain.
+func SignDepositData(sk *blsu.SecretKey, withdrawalCredentials Root, amount Gwei, genesisForkVersion Version) (*DepositData, error) {
+	pk, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deposit secret key: %w", err)
+	}
+	msg := DepositMessage{
+		Pubkey:                pk.Serialize(),
+		WithdrawalCredentials: withdrawalCredentials,
+		Amount:                amount,
+	}
+	sig, err := SignWithDomain(sk, &msg, ComputeDepositDomain(genesisForkVersion))
+	if err != nil {
+		return nil, err
+	}
+	return &DepositData{
+		Pubkey:                msg.Pubkey,
+		WithdrawalCredentials: msg.WithdrawalCredentials,
+		Amount:                msg.Amount,
+		Signature:             sig.Serialize(),
+	}, nil
+}
+
+// VerifyDepositData verifies the deposit signature, the proof-of-possession of the pubkey,
+// with the deposit domain of the genesis fork version.
+//
+// The deposit_data_root is returned, also if the deposit is invalid:
+// invalid deposits are still part of the deposit contract tree, and are only skipped when processed.
+// An invalid pubkey or signature encoding, the identity pubkey, or an invalid signature, all result in an error.
+func VerifyDepositData(data *DepositData, genesisForkVersion Version) (depositDataRoot Root, err error) {
+	depositDataRoot, _ = data.HashTreeRoot()
+	var pk blsu.Pubkey
+	if err := pk.Deserialize(&data.Pubkey); err != nil {
+		return depositDataRoot, fmt.Errorf("invalid deposit pubkey: %w", err)
+	}
+	var sig blsu.Signature
+	if err := sig.Deserialize(&data.Signature); err != nil {
+		return depositDataRoot, fmt.Errorf("invalid deposit signature: %w: %w", blsu.ErrInvalidSignature, err)
+	}
+	if err := VerifyWithDomain(&pk, data.Message(), ComputeDepositDomain(genesisForkVersion), &sig); err != nil {
+		return depositDataRoot, fmt.Errorf("invalid deposit: %w", err)
+	}
+	return depositDataRoot, nil
+}
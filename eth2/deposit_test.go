@@ -0,0 +1,109 @@
+package eth2
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	blsu "github.com/protolambda/bls12-381-util"
+	"testing"
+)
+
+func testSecretKey(t *testing.T, v byte) *blsu.SecretKey {
+	var skBytes [32]byte
+	skBytes[31] = v
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		t.Fatal(err)
+	}
+	return &sk
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+func TestDepositDataRoot(t *testing.T) {
+	data, err := SignDepositData(testSecretKey(t, 1), Root{0x01, 0x02}, 32_000_000_000, Version{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pk blsu.Pubkey
+	if err := pk.Deserialize(&data.Pubkey); err != nil {
+		t.Fatal(err)
+	}
+	var sig blsu.Signature
+	if err := sig.Deserialize(&data.Signature); err != nil {
+		t.Fatal(err)
+	}
+	pkRoot, _ := pk.HashTreeRoot()
+	sigRoot, _ := sig.HashTreeRoot()
+	var amount [32]byte
+	binary.LittleEndian.PutUint64(amount[:], uint64(data.Amount))
+
+	msgRoot, _ := data.Message().HashTreeRoot()
+	if msgRoot != hashPair(hashPair(pkRoot, data.WithdrawalCredentials), hashPair(amount, [32]byte{})) {
+		t.Fatalf("unexpected deposit message root: %x", msgRoot)
+	}
+	root, _ := data.HashTreeRoot()
+	if root != hashPair(hashPair(pkRoot, data.WithdrawalCredentials), hashPair(amount, sigRoot)) {
+		t.Fatalf("unexpected deposit data root: %x", root)
+	}
+}
+
+func TestVerifyDepositData(t *testing.T) {
+	genesisForkVersion := Version{0x00, 0x00, 0x10, 0x20}
+	valid, err := SignDepositData(testSecretKey(t, 1), Root{0x01}, 32_000_000_000, genesisForkVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := SignDepositData(testSecretKey(t, 2), Root{0x01}, 32_000_000_000, genesisForkVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongSigner := *valid
+	wrongSigner.Signature = other.Signature
+	wrongAmount := *valid
+	wrongAmount.Amount = 1_000_000_000
+	badPubkey := *valid
+	badPubkey.Pubkey[0] &^= 0x80
+	identityPubkey := *valid
+	identityPubkey.Pubkey = [48]byte{0xc0}
+	badSignature := *valid
+	badSignature.Signature[95] ^= 1
+	badSignatureEncoding := *valid
+	badSignatureEncoding.Signature[0] &^= 0x80
+	identitySignature := *valid
+	identitySignature.Signature = [96]byte{0xc0}
+
+	cases := []struct {
+		name    string
+		data    *DepositData
+		version Version
+		err     error
+	}{
+		{"valid", valid, genesisForkVersion, nil},
+		{"other fork version", valid, Version{}, blsu.ErrInvalidSignature},
+		{"wrong signer", &wrongSigner, genesisForkVersion, blsu.ErrInvalidSignature},
+		{"wrong amount", &wrongAmount, genesisForkVersion, blsu.ErrInvalidSignature},
+		{"invalid pubkey encoding", &badPubkey, genesisForkVersion, blsu.ErrPubkeyEncoding},
+		{"identity pubkey", &identityPubkey, genesisForkVersion, blsu.ErrIdentityPubkey},
+		{"invalid signature point", &badSignature, genesisForkVersion, blsu.ErrInvalidSignature},
+		{"invalid signature encoding", &badSignatureEncoding, genesisForkVersion, blsu.ErrInvalidSignature},
+		{"invalid signature encoding reason", &badSignatureEncoding, genesisForkVersion, blsu.ErrSignatureEncoding},
+		{"identity signature", &identitySignature, genesisForkVersion, blsu.ErrInvalidSignature},
+		{"identity signature reason", &identitySignature, genesisForkVersion, blsu.ErrIdentitySignature},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root, err := VerifyDepositData(c.data, c.version)
+			expectedRoot, _ := c.data.HashTreeRoot()
+			if root != expectedRoot {
+				t.Fatalf("unexpected deposit data root: %x", root)
+			}
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, got: %v", c.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package eth2 implements the Ethereum consensus signing domains and signing roots,
+// to sign and verify SSZ objects with the blsu POP ciphersuite.
+//
+// Spec: https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/beacon-chain.md#helper-functions
+package eth2
+
+import (
+	"crypto/sha256"
+	"fmt"
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+// DomainType is the 4-byte prefix of a Domain, separating signatures of different message types.
+type DomainType [4]byte
+
+// Version is a 4-byte fork version.
+type Version [4]byte
+
+// Root is a 32-byte SSZ hash_tree_root.
+type Root [32]byte
+
+// Domain is the 32-byte signature domain: the DomainType, followed by the first 28 bytes of the fork data root.
+type Domain [32]byte
+
+// Domain types, as specified in the consensus specs. These are not constants, since Go does not have array constants.
+var (
+	DomainBeaconProposer              = DomainType{0x00, 0x00, 0x00, 0x00}
+	DomainBeaconAttester              = DomainType{0x01, 0x00, 0x00, 0x00}
+	DomainRandao                      = DomainType{0x02, 0x00, 0x00, 0x00}
+	DomainDeposit                     = DomainType{0x03, 0x00, 0x00, 0x00}
+	DomainVoluntaryExit               = DomainType{0x04, 0x00, 0x00, 0x00}
+	DomainSelectionProof              = DomainType{0x05, 0x00, 0x00, 0x00}
+	DomainAggregateAndProof           = DomainType{0x06, 0x00, 0x00, 0x00}
+	DomainSyncCommittee               = DomainType{0x07, 0x00, 0x00, 0x00}
+	DomainSyncCommitteeSelectionProof = DomainType{0x08, 0x00, 0x00, 0x00}
+	DomainContributionAndProof        = DomainType{0x09, 0x00, 0x00, 0x00}
+	DomainBLSToExecutionChange        = DomainType{0x0a, 0x00, 0x00, 0x00}
+	DomainApplicationMask             = DomainType{0x00, 0x00, 0x00, 0x01}
+	// DomainApplicationBuilder is the builder-specs domain type, for validator registrations and builder bids.
+	DomainApplicationBuilder = DomainType{0x00, 0x00, 0x00, 0x01}
+)
+
+// HashTreeRoot returns the root itself: the hash_tree_root of a Bytes32 is the value itself.
+// This allows a precomputed root to be signed with SignWithDomain.
+func (r Root) HashTreeRoot() ([32]byte, error) {
+	return r, nil
+}
+
+// HashRoot is an SSZ object that can be merkleized,
+// implemented by Root, *blsu.Pubkey and *blsu.Signature.
+// The key types implement it on the pointer, and a nil *blsu.Pubkey or *blsu.Signature panics,
+// like their Serialize: check for nil before passing them as HashRoot.
+type HashRoot interface {
+	HashTreeRoot() ([32]byte, error)
+}
+
+// ComputeForkDataRoot returns the hash_tree_root of the ForkData container.
+//
+//	def compute_fork_data_root(current_version: Version, genesis_validators_root: Root) -> Root
+func ComputeForkDataRoot(currentVersion Version, genesisValidatorsRoot Root) Root {
+	var data [64]byte
+	copy(data[:4], currentVersion[:])
+	copy(data[32:], genesisValidatorsRoot[:])
+	return sha256.Sum256(data[:])
+}
+
+// ComputeDomain returns the domain for the domain type and fork version.
+// The spec defaults to the genesis fork version and a zero genesis validators root (e.g. for deposits):
+// the caller passes these explicitly.
+//
+//	def compute_domain(domain_type: DomainType, fork_version: Version=None, genesis_validators_root: Root=None) -> Domain
+func ComputeDomain(domainType DomainType, forkVersion Version, genesisValidatorsRoot Root) (out Domain) {
+	forkDataRoot := ComputeForkDataRoot(forkVersion, genesisValidatorsRoot)
+	copy(out[:4], domainType[:])
+	copy(out[4:], forkDataRoot[:28])
+	return
+}
+
+// ComputeSigningRoot returns the hash_tree_root of the SigningData container, with the root of the object and the domain.
+//
+//	def compute_signing_root(ssz_object: SSZObject, domain: Domain) -> Root
+func ComputeSigningRoot(obj HashRoot, domain Domain) (Root, error) {
+	objectRoot, err := obj.HashTreeRoot()
+	if err != nil {
+		return Root{}, fmt.Errorf("failed to compute object root: %w", err)
+	}
+	var data [64]byte
+	copy(data[:32], objectRoot[:])
+	copy(data[32:], domain[:])
+	return sha256.Sum256(data[:]), nil
+}
+
+// SignWithDomain signs the signing root of the object and domain, with the POP ciphersuite (blsu.Sign).
+func SignWithDomain(sk *blsu.SecretKey, obj HashRoot, domain Domain) (*blsu.Signature, error) {
+	signingRoot, err := ComputeSigningRoot(obj, domain)
+	if err != nil {
+		return nil, err
+	}
+	return blsu.Sign(sk, signingRoot[:]), nil
+}
+
+// VerifyWithDomain verifies the signature of the signing root of the object and domain,
+// with the POP ciphersuite (blsu.VerifyDetailed). It returns nil if the signature is VALID.
+func VerifyWithDomain(pk *blsu.Pubkey, obj HashRoot, domain Domain, signature *blsu.Signature) error {
+	signingRoot, err := ComputeSigningRoot(obj, domain)
+	if err != nil {
+		return err
+	}
+	return blsu.VerifyDetailed(pk, signingRoot[:], signature)
+}
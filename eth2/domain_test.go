@@ -0,0 +1,88 @@
+package eth2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	blsu "github.com/protolambda/bls12-381-util"
+	"testing"
+)
+
+func TestComputeDomain(t *testing.T) {
+	// the mainnet deposit domain: genesis fork version 0x00000000 and a zero genesis validators root
+	domain := ComputeDomain(DomainDeposit, Version{}, Root{})
+	if got := hex.EncodeToString(domain[:]); got != "03000000f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a9" {
+		t.Fatalf("unexpected deposit domain: %s", got)
+	}
+
+	version := Version{1, 2, 3, 4}
+	var genesisValidatorsRoot Root
+	genesisValidatorsRoot[0] = 0xaa
+	genesisValidatorsRoot[31] = 0xbb
+	domain = ComputeDomain(DomainBeaconAttester, version, genesisValidatorsRoot)
+	var forkData [64]byte
+	copy(forkData[:], version[:])
+	copy(forkData[32:], genesisValidatorsRoot[:])
+	forkDataRoot := sha256.Sum256(forkData[:])
+	if forkDataRoot != ComputeForkDataRoot(version, genesisValidatorsRoot) {
+		t.Fatal("unexpected fork data root")
+	}
+	if [4]byte(domain[:4]) != DomainBeaconAttester || [28]byte(domain[4:]) != [28]byte(forkDataRoot[:28]) {
+		t.Fatalf("unexpected domain: %x", domain)
+	}
+}
+
+type failingObject struct{}
+
+func (failingObject) HashTreeRoot() ([32]byte, error) {
+	return [32]byte{}, errors.New("test error")
+}
+
+func TestSignWithDomain(t *testing.T) {
+	var skBytes [32]byte
+	skBytes[31] = 42
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		t.Fatal(err)
+	}
+	pk, err := blsu.SkToPk(&sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := ComputeDomain(DomainRandao, Version{0, 0, 0, 1}, Root{1})
+	epoch := Root{7}
+
+	sig, err := SignWithDomain(&sk, epoch, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyWithDomain(pk, epoch, domain, sig); err != nil {
+		t.Fatalf("expected valid signature: %v", err)
+	}
+	signingRoot, err := ComputeSigningRoot(epoch, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := sha256.Sum256(append(epoch[:], domain[:]...)); signingRoot != expected {
+		t.Fatalf("unexpected signing root: %x", signingRoot)
+	}
+	if !blsu.Verify(pk, signingRoot[:], sig) {
+		t.Fatal("expected signature over the signing root")
+	}
+
+	otherDomain := ComputeDomain(DomainRandao, Version{0, 0, 0, 2}, Root{1})
+	if err := VerifyWithDomain(pk, epoch, otherDomain, sig); !errors.Is(err, blsu.ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature with other domain, got: %v", err)
+	}
+
+	// SSZ objects of the blsu package can be signed too
+	if _, err := SignWithDomain(&sk, pk, domain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignWithDomain(&sk, failingObject{}, domain); err == nil {
+		t.Fatal("expected object root error")
+	}
+	if err := VerifyWithDomain(pk, failingObject{}, domain, sig); err == nil {
+		t.Fatal("expected object root error")
+	}
+}
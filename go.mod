@@ -2,6 +2,9 @@ module github.com/protolambda/bls12-381-util
 
 go 1.21
 
-require github.com/kilic/bls12-381 v0.1.0
-
-require golang.org/x/sys v0.17.0 // indirect
+require (
+	github.com/kilic/bls12-381 v0.1.0
+	golang.org/x/crypto v0.19.0
+	golang.org/x/sys v0.17.0
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,157 @@
+package blsu
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// Zeroize overwrites the secret key with zeroes. The secret key is invalid afterwards.
+func (sk *SecretKey) Zeroize() {
+	*sk = SecretKey{}
+}
+
+// zeroBytes overwrites the given secret bytes with zeroes.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// ErrGuardedClosed is returned when the secret keys are accessed after the container is closed.
+var ErrGuardedClosed = errors.New("guarded secret keys are closed")
+
+// GuardedSecretKeys is a fixed-size container of secret keys,
+// allocated outside of the Go heap, and locked in memory to not be swapped to disk, where the OS supports it.
+// The key material is wiped when the container is closed.
+// The keys are never exposed by reference: Use gives access while the memory is held, Key returns a copy.
+type GuardedSecretKeys struct {
+	mu     sync.RWMutex
+	mem    []byte
+	locked bool
+	keys   []SecretKey
+}
+
+// GuardedOptions configures the memory of GuardedSecretKeys.
+type GuardedOptions struct {
+	// AllowUnlocked falls back to unlocked memory if the memory cannot be locked,
+	// e.g. when the keys exceed the memlock rlimit, which is commonly 64 KiB (2048 keys) in containers and services.
+	// The keys are still kept outside of the Go heap, and wiped on Close, but may be swapped to disk.
+	// Check Locked to warn about the fallback.
+	AllowUnlocked bool
+}
+
+// NewGuardedSecretKeys allocates guarded memory for n secret keys. The keys are zero until they are set.
+// The container must be closed to release the memory.
+// Allocation fails if the memory cannot be locked, including on platforms without memory locks,
+// see NewGuardedSecretKeysWithOptions for a fallback.
+func NewGuardedSecretKeys(n int) (*GuardedSecretKeys, error) {
+	return NewGuardedSecretKeysWithOptions(n, nil)
+}
+
+// NewGuardedSecretKeysWithOptions is NewGuardedSecretKeys, with options. The options may be nil to use the defaults.
+func NewGuardedSecretKeysWithOptions(n int, opts *GuardedOptions) (*GuardedSecretKeys, error) {
+	if opts == nil {
+		opts = &GuardedOptions{}
+	}
+	if n <= 0 {
+		return nil, errors.New("need at least 1 secret key")
+	}
+	size := int(unsafe.Sizeof(SecretKey{})) * n
+	mem, locked, err := allocGuarded(size, opts.AllowUnlocked)
+	if err != nil {
+		return nil, err
+	}
+	// SecretKey contains no pointers, it is safe to keep outside of the Go heap
+	keys := unsafe.Slice((*SecretKey)(unsafe.Pointer(&mem[0])), n)
+	return &GuardedSecretKeys{mem: mem, locked: locked, keys: keys}, nil
+}
+
+// Locked returns true if the memory is locked, and cannot be swapped to disk.
+// It is false if the memory lock is not supported on this platform, or failed with GuardedOptions.AllowUnlocked.
+func (g *GuardedSecretKeys) Locked() bool {
+	return g.locked
+}
+
+// Set moves the secret key into the guarded memory at index i: the given secret key is zeroized after copying.
+// The given secret key is left untouched if the container is closed, or the index is out of range.
+func (g *GuardedSecretKeys) Set(i int, sk *SecretKey) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.check(i); err != nil {
+		return err
+	}
+	g.keys[i] = *sk
+	sk.Zeroize()
+	return nil
+}
+
+// deserialize deserializes the secret key directly into the guarded memory at index i, see SecretKey.Deserialize.
+// The slot is zeroized if the secret key is invalid.
+func (g *GuardedSecretKeys) deserialize(i int, raw *[32]byte) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.check(i); err != nil {
+		return err
+	}
+	if err := g.keys[i].Deserialize(raw); err != nil {
+		g.keys[i].Zeroize()
+		return err
+	}
+	return nil
+}
+
+// Use calls fn with the secret key at index i, and returns the error of fn.
+// The container cannot be closed while fn runs, and fn must not keep the pointer after it returns,
+// or call Set or Close on the same container.
+func (g *GuardedSecretKeys) Use(i int, fn func(sk *SecretKey) error) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if err := g.check(i); err != nil {
+		return err
+	}
+	return fn(&g.keys[i])
+}
+
+// Key returns a copy of the secret key at index i.
+// The copy lives outside of the guarded memory: the caller should zeroize it after use, or prefer Use.
+func (g *GuardedSecretKeys) Key(i int) (out SecretKey, err error) {
+	err = g.Use(i, func(sk *SecretKey) error {
+		out = *sk
+		return nil
+	})
+	return
+}
+
+// check returns an error if the container is closed, or i is out of range. The caller must hold the lock.
+func (g *GuardedSecretKeys) check(i int) error {
+	if g.mem == nil {
+		return ErrGuardedClosed
+	}
+	if i < 0 || i >= len(g.keys) {
+		return fmt.Errorf("secret key index %d out of range, container has %d keys", i, len(g.keys))
+	}
+	return nil
+}
+
+// Len returns the number of secret keys in the container, or 0 if it is closed.
+func (g *GuardedSecretKeys) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.keys)
+}
+
+// Close wipes the secret keys, and releases the guarded memory. Close is a no-op if the container is already closed.
+func (g *GuardedSecretKeys) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.mem == nil {
+		return nil
+	}
+	zeroBytes(g.mem)
+	err := freeGuarded(g.mem, g.locked)
+	g.mem = nil
+	g.keys = nil
+	return err
+}
@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package blsu
+
+import "errors"
+
+// allocGuarded allocates regular memory if allowUnlocked is true: locking memory is not supported on this platform,
+// the memory is always unlocked. The memory is still wiped on Close.
+func allocGuarded(size int, allowUnlocked bool) (mem []byte, locked bool, err error) {
+	if !allowUnlocked {
+		return nil, false, errors.New("locking guarded memory is not supported on this platform (allow unlocked memory instead)")
+	}
+	return make([]byte, size, size), false, nil
+}
+
+// freeGuarded is a no-op, the memory is garbage-collected. The memory is expected to be wiped already.
+func freeGuarded(mem []byte, locked bool) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package blsu
+
+import "testing"
+
+// guardedLockSupported is true if guarded memory can be locked on this platform,
+// otherwise the tests need to allow unlocked memory.
+const guardedLockSupported = false
+
+func TestGuardedSecretKeysUnlocked(t *testing.T) {
+	if _, err := NewGuardedSecretKeys(1); err == nil {
+		t.Fatal("expected locking to be unsupported on this platform")
+	}
+	g, err := NewGuardedSecretKeysWithOptions(1, &GuardedOptions{AllowUnlocked: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Locked() {
+		t.Fatal("expected fallback to unlocked memory")
+	}
+	if err := g.Set(0, randSK(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package blsu
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+)
+
+// allocGuarded maps anonymous memory of at least the given size, and locks it, to not be swapped to disk.
+// If locking fails and allowUnlocked is true, the memory is returned unlocked.
+func allocGuarded(size int, allowUnlocked bool) (mem []byte, locked bool, err error) {
+	pageSize := unix.Getpagesize()
+	size = ((size + pageSize - 1) / pageSize) * pageSize
+	mem, err = unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to map guarded memory: %v", err)
+	}
+	if err := unix.Mlock(mem); err != nil {
+		if allowUnlocked {
+			return mem, false, nil
+		}
+		_ = unix.Munmap(mem)
+		return nil, false, fmt.Errorf("failed to lock guarded memory (check the memlock rlimit, or allow unlocked memory): %v", err)
+	}
+	return mem, true, nil
+}
+
+// freeGuarded unlocks, if locked, and unmaps the memory. The memory is expected to be wiped already.
+func freeGuarded(mem []byte, locked bool) error {
+	if locked {
+		if err := unix.Munlock(mem); err != nil {
+			return fmt.Errorf("failed to unlock guarded memory: %v", err)
+		}
+	}
+	if err := unix.Munmap(mem); err != nil {
+		return fmt.Errorf("failed to unmap guarded memory: %v", err)
+	}
+	return nil
+}
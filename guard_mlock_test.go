@@ -0,0 +1,49 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package blsu
+
+import (
+	"golang.org/x/sys/unix"
+	"testing"
+)
+
+// guardedLockSupported is true if guarded memory can be locked on this platform,
+// otherwise the tests need to allow unlocked memory.
+const guardedLockSupported = true
+
+func TestGuardedSecretKeysUnlocked(t *testing.T) {
+	if unix.Geteuid() == 0 {
+		t.Skip("memory locks are not limited for root")
+	}
+	var prev unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &prev); err != nil {
+		t.Fatal(err)
+	}
+	limit := prev
+	limit.Cur = 0
+	if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+		t.Skipf("cannot lower the memlock rlimit: %v", err)
+	}
+	defer func() {
+		if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &prev); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := NewGuardedSecretKeys(1); err == nil {
+		t.Fatal("expected locking to fail without memlock rlimit")
+	}
+	g, err := NewGuardedSecretKeysWithOptions(1, &GuardedOptions{AllowUnlocked: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Locked() {
+		t.Fatal("expected fallback to unlocked memory")
+	}
+	if err := g.Set(0, randSK(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
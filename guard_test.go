@@ -0,0 +1,82 @@
+package blsu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSecretKeyZeroize(t *testing.T) {
+	sk := randSK(t)
+	sk.Zeroize()
+	if *sk != (SecretKey{}) {
+		t.Fatal("expected zeroized secret key")
+	}
+}
+
+func TestGuardedSecretKeys(t *testing.T) {
+	g, err := NewGuardedSecretKeysWithOptions(3, &GuardedOptions{AllowUnlocked: !guardedLockSupported})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hello")
+	for i := 0; i < g.Len(); i++ {
+		sk := randSK(t)
+		expected := Sign(sk, msg).Serialize()
+		if err := g.Set(i, sk); err != nil {
+			t.Fatal(err)
+		}
+		if *sk != (SecretKey{}) {
+			t.Fatal("expected source key to be zeroized after move")
+		}
+		err := g.Use(i, func(sk *SecretKey) error {
+			if got := Sign(sk, msg).Serialize(); got != expected {
+				t.Fatalf("guarded key %d signs differently", i)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cpy, err := g.Key(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Sign(&cpy, msg).Serialize(); got != expected {
+			t.Fatalf("copy of guarded key %d signs differently", i)
+		}
+	}
+	if err := g.Set(g.Len(), randSK(t)); err == nil {
+		t.Fatal("expected out of range index to fail")
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// access after close fails, instead of touching the released memory
+	sk := randSK(t)
+	if err := g.Set(0, sk); !errors.Is(err, ErrGuardedClosed) {
+		t.Fatalf("expected set after close to fail, got %v", err)
+	}
+	if *sk == (SecretKey{}) {
+		t.Fatal("expected source key to be kept when set fails")
+	}
+	if _, err := g.Key(0); !errors.Is(err, ErrGuardedClosed) {
+		t.Fatalf("expected key after close to fail, got %v", err)
+	}
+	if err := g.Use(0, func(sk *SecretKey) error { return nil }); !errors.Is(err, ErrGuardedClosed) {
+		t.Fatalf("expected use after close to fail, got %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatalf("expected second close to be a no-op: %v", err)
+	}
+	locked, err := NewGuardedSecretKeysWithOptions(1, &GuardedOptions{AllowUnlocked: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("guarded memory locked: %v", locked.Locked())
+	if err := locked.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGuardedSecretKeys(0); err == nil {
+		t.Fatal("expected empty container to fail")
+	}
+}
@@ -0,0 +1,64 @@
+package blsu
+
+import (
+	"crypto/sha256"
+	"errors"
+	kbls "github.com/kilic/bls12-381"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// KeyGen salt, as specified in the IETF signature draft v4
+var keyGenSalt = []byte("BLS-SIG-KEYGEN-SALT-")
+
+// L = ceil((3 * ceil(log2(r))) / 16), where r is the order of the BLS12-381 subgroup
+const keyGenL = 48
+
+// hkdfModR derives a secret scalar from the input keying material,
+// repeating the derivation with a re-hashed salt in the unlikely case the result is zero.
+func hkdfModR(ikm []byte, keyInfo []byte) (*SecretKey, error) {
+	// 1. salt = "BLS-SIG-KEYGEN-SALT-"
+	salt := keyGenSalt
+	// 2. SK = 0
+	var sk kbls.Fr
+	// IKM || I2OSP(0, 1)
+	ikmPadded := make([]byte, len(ikm)+1, len(ikm)+1)
+	copy(ikmPadded, ikm)
+	// key_info || I2OSP(L, 2)
+	info := make([]byte, len(keyInfo)+2, len(keyInfo)+2)
+	copy(info, keyInfo)
+	info[len(keyInfo)] = 0
+	info[len(keyInfo)+1] = keyGenL
+	var okm [keyGenL]byte
+	defer zeroBytes(okm[:])
+	defer zeroBytes(ikmPadded)
+	// 3. while SK == 0:
+	for sk.IsZero() {
+		// 4. salt = H(salt)
+		h := sha256.Sum256(salt)
+		salt = h[:]
+		// 5. PRK = HKDF-Extract(salt, IKM || I2OSP(0, 1))
+		prk := hkdf.Extract(sha256.New, ikmPadded, salt)
+		// 6. OKM = HKDF-Expand(PRK, key_info || I2OSP(L, 2), L)
+		_, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), okm[:])
+		zeroBytes(prk)
+		if err != nil {
+			return nil, err
+		}
+		// 7. SK = OS2IP(OKM) mod r
+		sk.FromBytes(okm[:])
+	}
+	// 8. return SK
+	return (*SecretKey)(&sk), nil
+}
+
+// The KeyGen algorithm generates a secret key SK deterministically from a secret octet string IKM,
+// and an optional octet string keyInfo (nil or empty if not used).
+//
+// IKM MUST be at least 32 bytes long, and SHOULD be generated from a secure source of randomness.
+func KeyGen(ikm []byte, keyInfo []byte) (*SecretKey, error) {
+	if len(ikm) < 32 {
+		return nil, errors.New("IKM must be at least 32 bytes")
+	}
+	return hkdfModR(ikm, keyInfo)
+}
@@ -0,0 +1,338 @@
+package blsu
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// KeyManagerOptions configures LoadKeyManager. The zero value is valid.
+type KeyManagerOptions struct {
+	// Workers is the maximum number of keystores that are decrypted in parallel.
+	// Each scrypt keystore decryption with default parameters uses 256 MiB of memory.
+	// Defaults to GOMAXPROCS.
+	Workers int
+	// AllowUnlockedMemory keeps the secret keys in unlocked memory if the guarded memory cannot be locked,
+	// instead of failing to load, see GuardedOptions.AllowUnlocked. Check Locked to warn about the fallback.
+	AllowUnlockedMemory bool
+}
+
+// KeyManager holds the decrypted secret keys of a directory of EIP-2335 keystores.
+// The secret keys are kept in guarded memory, see GuardedSecretKeys, and wiped on Close.
+// Each keystore is locked with a lockfile while the KeyManager is open,
+// to prevent other processes from using the same keys, see KeyManager.Close.
+// Where supported, the lockfile holds an advisory lock (flock), which is released by the OS if the process dies,
+// so a lockfile left behind by a crash does not block the next KeyManager.
+// On other platforms the lockfile is created exclusively, and a stale lockfile must be removed manually.
+type KeyManager struct {
+	mu        sync.RWMutex
+	guarded   *GuardedSecretKeys
+	keys      map[[48]byte]int // index of the secret key in guarded memory
+	lockFiles []*os.File
+	skipped   []string
+}
+
+type keystoreEntry struct {
+	path     string
+	keystore Keystore
+	password []byte // zeroized after decryption
+}
+
+// keystoreShape is the part of a JSON file that identifies it as an EIP-2335 keystore,
+// e.g. to tell keystores apart from the deposit data files that are commonly stored next to them.
+type keystoreShape struct {
+	Crypto  json.RawMessage `json:"crypto"`
+	Version uint            `json:"version"`
+}
+
+// isKeystore returns true if the JSON data is an object with a crypto section and the EIP-2335 version.
+func isKeystore(data []byte) bool {
+	var shape keystoreShape
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return false
+	}
+	return len(shape.Crypto) != 0 && string(shape.Crypto) != "null" && shape.Version == keystoreVersion
+}
+
+// lockFileSuffix is appended to the keystore path to get the path of its lockfile.
+const lockFileSuffix = ".lock"
+
+// errLockHeld is returned by openLockFile if another KeyManager holds the lock.
+var errLockHeld = errors.New("lock is held")
+
+// acquireLock locks the lockfile of the keystore, see openLockFile, and writes the PID of the process to it.
+// The lock is held until the returned file is closed, see releaseLock.
+func acquireLock(keystorePath string) (*os.File, error) {
+	lockPath := keystorePath + lockFileSuffix
+	f, err := openLockFile(lockPath)
+	if err != nil {
+		if errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("keystore %q is locked by another process, see %q", keystorePath, lockPath)
+		}
+		return nil, fmt.Errorf("failed to lock lockfile %q: %v", lockPath, err)
+	}
+	// the PID is only informational, the lock itself is held by the file, see openLockFile
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	if err != nil {
+		_ = releaseLock(f)
+		return nil, fmt.Errorf("failed to write lockfile %q: %v", lockPath, err)
+	}
+	return f, nil
+}
+
+// releaseLock removes and unlocks the lockfile, see closeLockFile.
+func releaseLock(f *os.File) error {
+	if err := closeLockFile(f); err != nil {
+		return fmt.Errorf("failed to release lockfile %q: %v", f.Name(), err)
+	}
+	return nil
+}
+
+// findPassword reads the password of the keystore from the passwords directory.
+// The password file is named after the 0x-prefixed hex pubkey of the keystore,
+// or after the keystore file name with a ".txt" extension instead of ".json".
+// Trailing newlines do not need to be trimmed, control codes are stripped from keystore passwords.
+// The caller must zeroize the password after use.
+func findPassword(passwordsDir string, keystorePath string, ks *Keystore) ([]byte, error) {
+	var candidates []string
+	if len(ks.Pubkey) != 0 {
+		candidates = append(candidates, "0x"+hex.EncodeToString(ks.Pubkey))
+	}
+	name := filepath.Base(keystorePath)
+	candidates = append(candidates, strings.TrimSuffix(name, filepath.Ext(name))+".txt")
+	for _, c := range candidates {
+		data, err := os.ReadFile(filepath.Join(passwordsDir, c))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read password file of keystore %q: %v", keystorePath, err)
+		}
+	}
+	return nil, fmt.Errorf("no password file found for keystore %q, tried: %s", keystorePath, strings.Join(candidates, ", "))
+}
+
+// LoadKeyManager finds all ".json" keystore files in the keystores directory and its sub-directories,
+// matches each of them with a password file in the passwords directory,
+// locks them, and decrypts them in parallel.
+// The options may be nil to use the defaults.
+//
+// JSON files that are not EIP-2335 keystores, i.e. not an object with a crypto section and version 4,
+// such as the deposit data files of the staking deposit CLI, are skipped, see Skipped.
+// The passwords are zeroized after decryption.
+//
+// If any keystore fails to load, all acquired locks are released, and an error is returned.
+func LoadKeyManager(keystoresDir string, passwordsDir string, opts *KeyManagerOptions) (*KeyManager, error) {
+	if opts == nil {
+		opts = &KeyManagerOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var entries []*keystoreEntry
+	defer func() {
+		for _, entry := range entries {
+			zeroBytes(entry.password)
+		}
+	}()
+	var skipped []string
+	err := filepath.WalkDir(keystoresDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !isKeystore(data) {
+			skipped = append(skipped, path)
+			return nil
+		}
+		entry := &keystoreEntry{path: path}
+		if err := json.Unmarshal(data, &entry.keystore); err != nil {
+			return fmt.Errorf("failed to decode keystore %q: %v", path, err)
+		}
+		entry.password, err = findPassword(passwordsDir, path, &entry.keystore)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	km := &KeyManager{keys: make(map[[48]byte]int, len(entries)), skipped: skipped}
+	for _, entry := range entries {
+		lockFile, err := acquireLock(entry.path)
+		if err != nil {
+			_ = km.Close()
+			return nil, err
+		}
+		km.lockFiles = append(km.lockFiles, lockFile)
+	}
+
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+	fail := func(err error) (*KeyManager, error) {
+		_ = km.Close()
+		return nil, err
+	}
+	if len(entries) > 0 {
+		// the keystores are decrypted directly into guarded memory
+		guarded, err := NewGuardedSecretKeysWithOptions(len(entries), &GuardedOptions{AllowUnlocked: opts.AllowUnlockedMemory})
+		if err != nil {
+			return fail(err)
+		}
+		km.guarded = guarded
+	}
+	errs := make([]error, len(entries), len(entries))
+	jobs := make(chan int, len(entries))
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = entries[i].keystore.decryptGuarded(entries[i].password, km.guarded, i)
+				zeroBytes(entries[i].password)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, entry := range entries {
+		if errs[i] != nil {
+			return fail(fmt.Errorf("failed to decrypt keystore %q: %v", entry.path, errs[i]))
+		}
+		var pub *Pubkey
+		err := km.guarded.Use(i, func(sk *SecretKey) (err error) {
+			pub, err = SkToPk(sk)
+			return err
+		})
+		if err != nil {
+			return fail(fmt.Errorf("invalid secret key in keystore %q: %v", entry.path, err))
+		}
+		pubRaw := pub.Serialize()
+		if len(entry.keystore.Pubkey) != 0 && string(entry.keystore.Pubkey) != string(pubRaw[:]) {
+			return fail(fmt.Errorf("keystore %q pubkey does not match its secret key", entry.path))
+		}
+		if _, ok := km.keys[pubRaw]; ok {
+			return fail(fmt.Errorf("duplicate key in keystore %q: 0x%x", entry.path, pubRaw[:]))
+		}
+		km.keys[pubRaw] = i
+	}
+	return km, nil
+}
+
+// Use calls fn with the secret key of the given pubkey, and returns the error of fn.
+// The secret key stays in guarded memory, and the KeyManager cannot be closed while fn runs.
+// fn must not keep the pointer after it returns, or call Close.
+// An error is returned if the pubkey is not managed, or the KeyManager is closed.
+func (km *KeyManager) Use(pub *Pubkey, fn func(sk *SecretKey) error) error {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	if km.guarded == nil {
+		return ErrGuardedClosed
+	}
+	pubRaw := pub.Serialize()
+	i, ok := km.keys[pubRaw]
+	if !ok {
+		return fmt.Errorf("unknown pubkey 0x%x", pubRaw[:])
+	}
+	return km.guarded.Use(i, fn)
+}
+
+// Get returns a copy of the secret key of the given pubkey, if it is managed, and the KeyManager is not closed.
+// The copy lives outside of the guarded memory: the caller should zeroize it after use, or prefer Use.
+func (km *KeyManager) Get(pub *Pubkey) (*SecretKey, bool) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	i, ok := km.keys[pub.Serialize()]
+	if !ok {
+		return nil, false
+	}
+	sk, err := km.guarded.Key(i)
+	if err != nil {
+		return nil, false
+	}
+	return &sk, true
+}
+
+// Keys returns a mapping of serialized pubkeys to copies of the secret keys, empty if the KeyManager is closed.
+// The copies live outside of the guarded memory: the caller should zeroize them after use.
+func (km *KeyManager) Keys() map[[48]byte]*SecretKey {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	out := make(map[[48]byte]*SecretKey, len(km.keys))
+	for k, i := range km.keys {
+		sk, err := km.guarded.Key(i)
+		if err != nil {
+			break
+		}
+		out[k] = &sk
+	}
+	return out
+}
+
+// Skipped returns the paths of the ".json" files that were skipped by LoadKeyManager, because they are not keystores.
+func (km *KeyManager) Skipped() []string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return append([]string(nil), km.skipped...)
+}
+
+// Locked returns true if the secret keys are in locked memory, see GuardedSecretKeys.Locked.
+// It is false for an empty or closed KeyManager.
+func (km *KeyManager) Locked() bool {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return km.guarded != nil && km.guarded.Locked()
+}
+
+// Len returns the number of managed keys.
+func (km *KeyManager) Len() int {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return len(km.keys)
+}
+
+// Close wipes the secret keys, and removes and unlocks the keystore lockfiles.
+func (km *KeyManager) Close() error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.keys = nil
+	var result error
+	if km.guarded != nil {
+		result = km.guarded.Close()
+		km.guarded = nil
+	}
+	for _, lockFile := range km.lockFiles {
+		if err := releaseLock(lockFile); err != nil && result == nil {
+			result = err
+		}
+	}
+	km.lockFiles = nil
+	return result
+}
@@ -0,0 +1,29 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package blsu
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// openLockFile creates the lockfile exclusively, or returns errLockHeld if it already exists.
+// Without advisory locks the lockfile is not released when the process dies, and must be removed manually then.
+func openLockFile(lockPath string) (*os.File, error) {
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, errLockHeld
+	}
+	return f, err
+}
+
+// closeLockFile closes the lockfile, and then releases the lock by removing it.
+// Open files cannot be removed on all platforms, e.g. on Windows.
+func closeLockFile(f *os.File) error {
+	err := f.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package blsu
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"io/fs"
+	"os"
+)
+
+// openLockFile opens or creates the lockfile, and takes an exclusive advisory lock (flock) on it,
+// or returns errLockHeld if another open file holds the lock, also within the same process.
+// The OS releases the lock when the file is closed, or when the process dies,
+// thus a lockfile left behind after a crash is taken over, and its PID is overwritten by acquireLock.
+func openLockFile(lockPath string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+			_ = f.Close()
+			if errors.Is(err, unix.EWOULDBLOCK) {
+				return nil, errLockHeld
+			}
+			return nil, err
+		}
+		// The previous holder removes the lockfile before unlocking it, see releaseLock.
+		// If it was removed between the open and the lock, then the lock is on the removed file: retry.
+		opened, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		current, err := os.Stat(lockPath)
+		if err == nil && os.SameFile(opened, current) {
+			return f, nil
+		}
+		_ = f.Close()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// closeLockFile removes the lockfile, and then releases the lock by closing the file.
+// The lockfile is removed while the lock is still held, see openLockFile.
+func closeLockFile(f *os.File) error {
+	err := os.Remove(f.Name())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
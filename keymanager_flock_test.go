@@ -0,0 +1,42 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package blsu
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyManagerStaleLockfile(t *testing.T) {
+	keystoresDir, passwordsDir, _ := writeTestKeystores(t, 2)
+	// a lockfile left behind by a crashed process, without a lock held on it
+	lockPath := filepath.Join(keystoresDir, "keystore-0.json"+lockFileSuffix)
+	if err := os.WriteFile(lockPath, []byte("4294967295\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	km, err := LoadKeyManager(keystoresDir, passwordsDir, testKeyManagerOptions)
+	if err != nil {
+		t.Fatalf("expected stale lockfile to be taken over: %v", err)
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprintf("%d\n", os.Getpid()); string(data) != expected {
+		t.Fatalf("expected lockfile with PID %q, got %q", expected, data)
+	}
+	// the lock is held by the key manager, not by the existence of the lockfile
+	if _, err := openLockFile(lockPath); !errors.Is(err, errLockHeld) {
+		t.Fatalf("expected lock to be held, got %v", err)
+	}
+	if err := km.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lockPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected lockfile to be removed after close, got %v", err)
+	}
+}
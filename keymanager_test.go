@@ -0,0 +1,162 @@
+package blsu
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestKeystores(t *testing.T, n int) (keystoresDir string, passwordsDir string, sks []*SecretKey) {
+	keystoresDir = t.TempDir()
+	passwordsDir = t.TempDir()
+	for i := 0; i < n; i++ {
+		sk := randSK(t)
+		password := fmt.Sprintf("password %d", i)
+		data, err := EncryptKeystore(sk, password, &KeystoreOptions{ScryptN: 1 << 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := fmt.Sprintf("keystore-%d", i)
+		if err := os.WriteFile(filepath.Join(keystoresDir, name+".json"), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		passwordName := name + ".txt"
+		// alternate between the two supported password file names
+		if i%2 == 0 {
+			pub, err := SkToPk(sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubRaw := pub.Serialize()
+			passwordName = fmt.Sprintf("0x%x", pubRaw[:])
+		}
+		if err := os.WriteFile(filepath.Join(passwordsDir, passwordName), []byte(password+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		sks = append(sks, sk)
+	}
+	return
+}
+
+// testKeyManagerOptions allows unlocked memory on platforms without memory locks.
+var testKeyManagerOptions = &KeyManagerOptions{AllowUnlockedMemory: !guardedLockSupported}
+
+func TestLoadKeyManager(t *testing.T) {
+	keystoresDir, passwordsDir, sks := writeTestKeystores(t, 10)
+	km, err := LoadKeyManager(keystoresDir, passwordsDir, &KeyManagerOptions{Workers: 3, AllowUnlockedMemory: !guardedLockSupported})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if km.Len() != len(sks) {
+		t.Fatalf("expected %d keys, got %d", len(sks), km.Len())
+	}
+	for i, sk := range sks {
+		pub, err := SkToPk(sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := km.Get(pub)
+		if !ok {
+			t.Fatalf("missing key %d", i)
+		}
+		if got.Serialize() != sk.Serialize() {
+			t.Fatalf("key %d does not match", i)
+		}
+		err = km.Use(pub, func(got *SecretKey) error {
+			if got.Serialize() != sk.Serialize() {
+				t.Fatalf("used key %d does not match", i)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys := km.Keys(); len(keys) != len(sks) {
+		t.Fatalf("expected %d keys, got %d", len(sks), len(keys))
+	}
+
+	// the keystores are locked while the key manager is open
+	if _, err := LoadKeyManager(keystoresDir, passwordsDir, testKeyManagerOptions); err == nil {
+		t.Fatal("expected locked keystores to fail to load")
+	}
+	if err := km.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the keys are no longer accessible after close
+	pub, err := SkToPk(sks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := km.Get(pub); ok {
+		t.Fatal("expected no key after close")
+	}
+	if keys := km.Keys(); len(keys) != 0 {
+		t.Fatalf("expected no keys after close, got %d", len(keys))
+	}
+	if err := km.Use(pub, func(sk *SecretKey) error { return nil }); !errors.Is(err, ErrGuardedClosed) {
+		t.Fatalf("expected use after close to fail, got %v", err)
+	}
+	km, err = LoadKeyManager(keystoresDir, passwordsDir, testKeyManagerOptions)
+	if err != nil {
+		t.Fatalf("expected keystores to be unlocked after close: %v", err)
+	}
+	if err := km.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeyManagerSkipsNonKeystores(t *testing.T) {
+	keystoresDir, passwordsDir, sks := writeTestKeystores(t, 2)
+	// the staking deposit CLI writes the deposit data next to the keystores
+	depositData := filepath.Join(keystoresDir, "deposit_data-1700000000.json")
+	if err := os.WriteFile(depositData, []byte(`[{"pubkey": "aa", "amount": 32000000000}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	otherVersion := filepath.Join(keystoresDir, "other.json")
+	if err := os.WriteFile(otherVersion, []byte(`{"crypto": {}, "version": 3}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	km, err := LoadKeyManager(keystoresDir, passwordsDir, testKeyManagerOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer km.Close()
+	if km.Len() != len(sks) {
+		t.Fatalf("expected %d keys, got %d", len(sks), km.Len())
+	}
+	if skipped := fmt.Sprint(km.Skipped()); skipped != fmt.Sprint([]string{depositData, otherVersion}) {
+		t.Fatalf("unexpected skipped files: %s", skipped)
+	}
+}
+
+func TestLoadKeyManagerErrors(t *testing.T) {
+	t.Run("missing password", func(t *testing.T) {
+		keystoresDir, passwordsDir, _ := writeTestKeystores(t, 2)
+		if err := os.Remove(filepath.Join(passwordsDir, "keystore-1.txt")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKeyManager(keystoresDir, passwordsDir, testKeyManagerOptions); err == nil {
+			t.Fatal("expected missing password to fail")
+		}
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		keystoresDir, passwordsDir, _ := writeTestKeystores(t, 2)
+		if err := os.WriteFile(filepath.Join(passwordsDir, "keystore-1.txt"), []byte("wrong"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKeyManager(keystoresDir, passwordsDir, testKeyManagerOptions); err == nil {
+			t.Fatal("expected wrong password to fail")
+		}
+		// locks are released after failure
+		matches, err := filepath.Glob(filepath.Join(keystoresDir, "*"+lockFileSuffix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 0 {
+			t.Fatalf("expected no lockfiles, got %v", matches)
+		}
+	})
+}
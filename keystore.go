@@ -0,0 +1,370 @@
+package blsu
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode/utf8"
+)
+
+// EIP-2335, BLS12-381 keystore:
+// https://eips.ethereum.org/EIPS/eip-2335
+//
+
+const (
+	KeystoreKDFScrypt = "scrypt"
+	KeystoreKDFPBKDF2 = "pbkdf2"
+)
+
+const keystoreVersion = 4
+
+// Default KDF work parameters, as recommended in EIP-2335
+const (
+	DefaultScryptN = 262144
+	DefaultPBKDF2C = 262144
+)
+
+// Limits of the KDF parameters of a keystore, checked before deriving the decryption key,
+// to not use excessive memory or CPU time on a crafted keystore. Scrypt uses 128*N*r bytes of memory.
+const (
+	MaxScryptN  = 1 << 20
+	MaxScryptRP = 16
+	MaxPBKDF2C  = 1 << 24
+)
+
+type hexBytes []byte
+
+func (v hexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(v)), nil
+}
+
+func (v *hexBytes) UnmarshalText(text []byte) error {
+	dat, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil {
+		return err
+	}
+	*v = dat
+	return nil
+}
+
+// KeystoreModule is a kdf, checksum or cipher module of the keystore crypto section
+type KeystoreModule struct {
+	Function string          `json:"function"`
+	Params   json.RawMessage `json:"params"`
+	Message  hexBytes        `json:"message"`
+}
+
+type KeystoreCrypto struct {
+	KDF      KeystoreModule `json:"kdf"`
+	Checksum KeystoreModule `json:"checksum"`
+	Cipher   KeystoreModule `json:"cipher"`
+}
+
+// Keystore is the JSON structure of an EIP-2335 keystore
+type Keystore struct {
+	Crypto      KeystoreCrypto `json:"crypto"`
+	Description string         `json:"description,omitempty"`
+	Pubkey      hexBytes       `json:"pubkey,omitempty"`
+	Path        string         `json:"path"`
+	UUID        string         `json:"uuid"`
+	Version     uint           `json:"version"`
+}
+
+type scryptParams struct {
+	DKLen uint     `json:"dklen"`
+	N     uint     `json:"n"`
+	P     uint     `json:"p"`
+	R     uint     `json:"r"`
+	Salt  hexBytes `json:"salt"`
+}
+
+type pbkdf2Params struct {
+	DKLen uint     `json:"dklen"`
+	C     uint     `json:"c"`
+	PRF   string   `json:"prf"`
+	Salt  hexBytes `json:"salt"`
+}
+
+type cipherParams struct {
+	IV hexBytes `json:"iv"`
+}
+
+// KeystoreOptions configures EncryptKeystore. The zero value is valid, and uses scrypt with default parameters.
+type KeystoreOptions struct {
+	// KDF is either KeystoreKDFScrypt or KeystoreKDFPBKDF2. Defaults to scrypt.
+	KDF string
+	// ScryptN is the scrypt work factor. Defaults to DefaultScryptN.
+	ScryptN uint
+	// PBKDF2C is the pbkdf2 iteration count. Defaults to DefaultPBKDF2C.
+	PBKDF2C uint
+	// Path is the EIP-2334 path the key was derived with, optional.
+	Path string
+	// Description is an optional human-readable description of the keystore.
+	Description string
+}
+
+// processPassword applies NFKD normalization to the password, and strips the C0, C1 and Delete control codes.
+// The password is not modified, and the normalized copy is zeroized.
+func processPassword(password []byte) []byte {
+	normalized := norm.NFKD.Bytes(password)
+	out := make([]byte, 0, len(normalized))
+	for rest := normalized; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			continue
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	// the normalization returns the password itself if it is already normalized
+	if len(normalized) > 0 && (len(password) == 0 || &normalized[0] != &password[0]) {
+		zeroBytes(normalized)
+	}
+	return out
+}
+
+func (ks *Keystore) decryptionKey(password []byte) ([]byte, error) {
+	kdf := &ks.Crypto.KDF
+	switch kdf.Function {
+	case KeystoreKDFScrypt:
+		var params scryptParams
+		if err := json.Unmarshal(kdf.Params, &params); err != nil {
+			return nil, fmt.Errorf("invalid scrypt params: %v", err)
+		}
+		if params.DKLen != 32 {
+			return nil, fmt.Errorf("scrypt dklen must be 32: %d", params.DKLen)
+		}
+		if params.N < 2 || params.N > MaxScryptN || params.N&(params.N-1) != 0 {
+			return nil, fmt.Errorf("scrypt n must be a power of two, at most %d: %d", MaxScryptN, params.N)
+		}
+		if params.R == 0 || params.P == 0 || params.R > MaxScryptRP || params.P > MaxScryptRP/params.R {
+			return nil, fmt.Errorf("scrypt r and p must be positive, with r*p at most %d: r: %d, p: %d", MaxScryptRP, params.R, params.P)
+		}
+		return scrypt.Key(password, params.Salt, int(params.N), int(params.R), int(params.P), int(params.DKLen))
+	case KeystoreKDFPBKDF2:
+		var params pbkdf2Params
+		if err := json.Unmarshal(kdf.Params, &params); err != nil {
+			return nil, fmt.Errorf("invalid pbkdf2 params: %v", err)
+		}
+		if params.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf: %q", params.PRF)
+		}
+		if params.DKLen != 32 {
+			return nil, fmt.Errorf("pbkdf2 dklen must be 32: %d", params.DKLen)
+		}
+		if params.C == 0 || params.C > MaxPBKDF2C {
+			return nil, fmt.Errorf("pbkdf2 iteration count must be positive, at most %d: %d", MaxPBKDF2C, params.C)
+		}
+		return pbkdf2.Key(password, params.Salt, int(params.C), int(params.DKLen), sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %q", kdf.Function)
+	}
+}
+
+func aes128CTR(key []byte, iv []byte, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid iv length: %d", len(iv))
+	}
+	out := make([]byte, len(in), len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+// Decrypt decrypts the secret key with the given password.
+// The secret key is allocated on the Go heap, see DecryptGuarded to decrypt into guarded memory.
+func (ks *Keystore) Decrypt(password string) (*SecretKey, error) {
+	pw := []byte(password)
+	defer zeroBytes(pw)
+	secret, err := ks.decryptSecret(pw)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(secret[:])
+	var sk SecretKey
+	if err := sk.Deserialize(secret); err != nil {
+		return nil, err
+	}
+	return &sk, nil
+}
+
+// DecryptGuarded decrypts the secret key with the given password, directly into the guarded memory at index i.
+func (ks *Keystore) DecryptGuarded(password string, g *GuardedSecretKeys, i int) error {
+	pw := []byte(password)
+	defer zeroBytes(pw)
+	return ks.decryptGuarded(pw, g, i)
+}
+
+// decryptGuarded is DecryptGuarded with a password that the caller can zeroize after use.
+func (ks *Keystore) decryptGuarded(password []byte, g *GuardedSecretKeys, i int) error {
+	secret, err := ks.decryptSecret(password)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(secret[:])
+	return g.deserialize(i, secret)
+}
+
+// decryptSecret decrypts the serialized secret key. The caller must zeroize it after use.
+func (ks *Keystore) decryptSecret(password []byte) (*[32]byte, error) {
+	if ks.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version: %d", ks.Version)
+	}
+	pw := processPassword(password)
+	key, err := ks.decryptionKey(pw)
+	zeroBytes(pw)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(key)
+	// checksum = SHA256(decryption_key[16:32] | cipher_message)
+	if ks.Crypto.Checksum.Function != "sha256" {
+		return nil, fmt.Errorf("unsupported checksum function: %q", ks.Crypto.Checksum.Function)
+	}
+	h := sha256.New()
+	h.Write(key[16:32])
+	h.Write(ks.Crypto.Cipher.Message)
+	if subtle.ConstantTimeCompare(h.Sum(nil), ks.Crypto.Checksum.Message) != 1 {
+		return nil, errors.New("invalid keystore checksum, wrong password?")
+	}
+	if ks.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher function: %q", ks.Crypto.Cipher.Function)
+	}
+	var params cipherParams
+	if err := json.Unmarshal(ks.Crypto.Cipher.Params, &params); err != nil {
+		return nil, fmt.Errorf("invalid cipher params: %v", err)
+	}
+	if len(ks.Crypto.Cipher.Message) != 32 {
+		return nil, fmt.Errorf("expected 32 byte secret, got %d bytes", len(ks.Crypto.Cipher.Message))
+	}
+	secret, err := aes128CTR(key[:16], params.IV, ks.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, err
+	}
+	return (*[32]byte)(secret), nil
+}
+
+// DecryptKeystore decodes the JSON EIP-2335 keystore, and decrypts the secret key with the given password.
+func DecryptKeystore(data []byte, password string) (*SecretKey, error) {
+	var ks Keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("failed to decode keystore: %v", err)
+	}
+	return ks.Decrypt(password)
+}
+
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	// version 4, variant RFC 4122
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
+
+// NewKeystore encrypts the secret key with the given password into a new EIP-2335 keystore.
+// The options may be nil to use the defaults.
+func NewKeystore(sk *SecretKey, password string, opts *KeystoreOptions) (*Keystore, error) {
+	if opts == nil {
+		opts = &KeystoreOptions{}
+	}
+	pub, err := SkToPk(sk)
+	if err != nil {
+		return nil, err
+	}
+	var salt [32]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, fmt.Errorf("%w: salt: %v", ErrEntropy, err)
+	}
+	var iv [16]byte
+	if _, err := rand.Read(iv[:]); err != nil {
+		return nil, fmt.Errorf("%w: iv: %v", ErrEntropy, err)
+	}
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("%w: uuid: %v", ErrEntropy, err)
+	}
+	ks := &Keystore{
+		Description: opts.Description,
+		Path:        opts.Path,
+		UUID:        uuid,
+		Version:     keystoreVersion,
+	}
+	var kdfParams interface{}
+	switch opts.KDF {
+	case "", KeystoreKDFScrypt:
+		n := opts.ScryptN
+		if n == 0 {
+			n = DefaultScryptN
+		}
+		ks.Crypto.KDF.Function = KeystoreKDFScrypt
+		kdfParams = &scryptParams{DKLen: 32, N: n, P: 1, R: 8, Salt: salt[:]}
+	case KeystoreKDFPBKDF2:
+		c := opts.PBKDF2C
+		if c == 0 {
+			c = DefaultPBKDF2C
+		}
+		ks.Crypto.KDF.Function = KeystoreKDFPBKDF2
+		kdfParams = &pbkdf2Params{DKLen: 32, C: c, PRF: "hmac-sha256", Salt: salt[:]}
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %q", opts.KDF)
+	}
+	if ks.Crypto.KDF.Params, err = json.Marshal(kdfParams); err != nil {
+		return nil, err
+	}
+	ks.Crypto.KDF.Message = hexBytes{}
+	raw := []byte(password)
+	pw := processPassword(raw)
+	zeroBytes(raw)
+	key, err := ks.decryptionKey(pw)
+	zeroBytes(pw)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(key)
+	secret := sk.Serialize()
+	cipherMessage, err := aes128CTR(key[:16], iv[:], secret[:])
+	zeroBytes(secret[:])
+	if err != nil {
+		return nil, err
+	}
+	ks.Crypto.Cipher.Function = "aes-128-ctr"
+	if ks.Crypto.Cipher.Params, err = json.Marshal(&cipherParams{IV: iv[:]}); err != nil {
+		return nil, err
+	}
+	ks.Crypto.Cipher.Message = cipherMessage
+	h := sha256.New()
+	h.Write(key[16:32])
+	h.Write(cipherMessage)
+	ks.Crypto.Checksum.Function = "sha256"
+	ks.Crypto.Checksum.Params = json.RawMessage("{}")
+	ks.Crypto.Checksum.Message = h.Sum(nil)
+	pubRaw := pub.Serialize()
+	ks.Pubkey = pubRaw[:]
+	return ks, nil
+}
+
+// EncryptKeystore encrypts the secret key with the given password, and encodes it as JSON EIP-2335 keystore.
+// The options may be nil to use the defaults.
+func EncryptKeystore(sk *SecretKey, password string, opts *KeystoreOptions) ([]byte, error) {
+	ks, err := NewKeystore(sk, password, opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(ks, "", "  ")
+}
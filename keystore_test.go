@@ -0,0 +1,216 @@
+package blsu
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Official EIP-2335 test vectors, password is NFKD normalized to "testpassword🔑"
+const keystoreTestPassword = "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511"
+
+const keystoreTestSecret = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+
+const keystoreTestScrypt = `{
+    "crypto": {
+        "kdf": {
+            "function": "scrypt",
+            "params": {
+                "dklen": 32,
+                "n": 262144,
+                "p": 1,
+                "r": 8,
+                "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"
+            },
+            "message": ""
+        },
+        "checksum": {
+            "function": "sha256",
+            "params": {},
+            "message": "d2217fe5f3e9a1e34581ef8a78f7c9928e436d36dacc5e846690a5581e8ea484"
+        },
+        "cipher": {
+            "function": "aes-128-ctr",
+            "params": {
+                "iv": "264daa3f303d7259501c93d997d84fe6"
+            },
+            "message": "06ae90d55fe0a6e9c5c3bc5b170827b2e5cce3929ed3f116c2811e6366dfe20f"
+        }
+    },
+    "description": "This is a test keystore that uses scrypt to secure the secret.",
+    "pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+    "path": "m/12381/60/3141592653/589793238",
+    "uuid": "1d85ae20-35c5-4611-98e8-aa14a633906f",
+    "version": 4
+}`
+
+const keystoreTestPBKDF2 = `{
+    "crypto": {
+        "kdf": {
+            "function": "pbkdf2",
+            "params": {
+                "dklen": 32,
+                "c": 262144,
+                "prf": "hmac-sha256",
+                "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"
+            },
+            "message": ""
+        },
+        "checksum": {
+            "function": "sha256",
+            "params": {},
+            "message": "8a9f5d9912ed7e75ea794bc5a89bca5f193721d30868ade6f73043c6ea6febf1"
+        },
+        "cipher": {
+            "function": "aes-128-ctr",
+            "params": {
+                "iv": "264daa3f303d7259501c93d997d84fe6"
+            },
+            "message": "cee03fde2af33149775b7223e7845e4fb2c8ae1792e5f99fe9ecf474cc8c16ad"
+        }
+    },
+    "description": "This is a test keystore that uses PBKDF2 to secure the secret.",
+    "pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+    "path": "m/12381/60/0/0",
+    "uuid": "64625def-3331-4eea-ab6f-782f3ed16a83",
+    "version": 4
+}`
+
+func TestDecryptKeystore(t *testing.T) {
+	for name, data := range map[string]string{"scrypt": keystoreTestScrypt, "pbkdf2": keystoreTestPBKDF2} {
+		t.Run(name, func(t *testing.T) {
+			sk, err := DecryptKeystore([]byte(data), keystoreTestPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := sk.Serialize()
+			if got := hex.EncodeToString(out[:]); got != keystoreTestSecret {
+				t.Fatalf("expected secret %s, got %s", keystoreTestSecret, got)
+			}
+			if _, err := DecryptKeystore([]byte(data), "wrong password"); err == nil {
+				t.Fatal("expected wrong password to fail")
+			}
+		})
+	}
+}
+
+func TestDecryptKeystoreScryptLimits(t *testing.T) {
+	for _, params := range []string{
+		`{"dklen": 64, "n": 1024, "p": 1, "r": 8, "salt": ""}`,
+		`{"dklen": 32, "n": 1000, "p": 1, "r": 8, "salt": ""}`,
+		`{"dklen": 32, "n": 0, "p": 1, "r": 8, "salt": ""}`,
+		`{"dklen": 32, "n": 2097152, "p": 1, "r": 8, "salt": ""}`,
+		`{"dklen": 32, "n": 1024, "p": 0, "r": 8, "salt": ""}`,
+		`{"dklen": 32, "n": 1024, "p": 3, "r": 8, "salt": ""}`,
+		`{"dklen": 32, "n": 1024, "p": 1, "r": 18446744073709551615, "salt": ""}`,
+	} {
+		var ks Keystore
+		if err := json.Unmarshal([]byte(keystoreTestScrypt), &ks); err != nil {
+			t.Fatal(err)
+		}
+		ks.Crypto.KDF.Params = json.RawMessage(params)
+		if _, err := ks.Decrypt(keystoreTestPassword); err == nil {
+			t.Fatalf("expected scrypt params to be rejected: %s", params)
+		}
+	}
+}
+
+func TestDecryptKeystorePBKDF2Limits(t *testing.T) {
+	for _, params := range []string{
+		`{"dklen": 64, "c": 1024, "prf": "hmac-sha256", "salt": ""}`,
+		`{"dklen": 18446744073709551615, "c": 1024, "prf": "hmac-sha256", "salt": ""}`,
+		`{"dklen": 32, "c": 0, "prf": "hmac-sha256", "salt": ""}`,
+		`{"dklen": 32, "c": 33554432, "prf": "hmac-sha256", "salt": ""}`,
+		`{"dklen": 32, "c": 18446744073709551615, "prf": "hmac-sha256", "salt": ""}`,
+	} {
+		var ks Keystore
+		if err := json.Unmarshal([]byte(keystoreTestPBKDF2), &ks); err != nil {
+			t.Fatal(err)
+		}
+		ks.Crypto.KDF.Params = json.RawMessage(params)
+		if _, err := ks.Decrypt(keystoreTestPassword); err == nil {
+			t.Fatalf("expected pbkdf2 params to be rejected: %s", params)
+		}
+	}
+}
+
+func TestDecryptGuarded(t *testing.T) {
+	var ks Keystore
+	if err := json.Unmarshal([]byte(keystoreTestPBKDF2), &ks); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGuardedSecretKeysWithOptions(2, &GuardedOptions{AllowUnlocked: !guardedLockSupported})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.DecryptGuarded(keystoreTestPassword, g, 1); err != nil {
+		t.Fatal(err)
+	}
+	err = g.Use(1, func(sk *SecretKey) error {
+		out := sk.Serialize()
+		if got := hex.EncodeToString(out[:]); got != keystoreTestSecret {
+			t.Fatalf("expected secret %s, got %s", keystoreTestSecret, got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.DecryptGuarded("wrong password", g, 0); err == nil {
+		t.Fatal("expected wrong password to fail")
+	}
+	if err := ks.DecryptGuarded(keystoreTestPassword, g, 2); err == nil {
+		t.Fatal("expected out of range index to fail")
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.DecryptGuarded(keystoreTestPassword, g, 0); !errors.Is(err, ErrGuardedClosed) {
+		t.Fatalf("expected decryption into closed memory to fail, got: %v", err)
+	}
+}
+
+func TestProcessPassword(t *testing.T) {
+	password := []byte(keystoreTestPassword)
+	got := processPassword(password)
+	if hex.EncodeToString(got) != "7465737470617373776f7264f09f9491" {
+		t.Fatalf("unexpected processed password: %x", got)
+	}
+	if string(password) != keystoreTestPassword {
+		t.Fatal("expected the password to not be modified")
+	}
+	got = processPassword([]byte("a\x00b\x1fc\x7fd\u0080e\u009ff\n"))
+	if string(got) != "abcdef" {
+		t.Fatalf("expected control codes to be stripped, got %q", got)
+	}
+}
+
+func TestEncryptKeystore(t *testing.T) {
+	sk := randSK(t)
+	for _, opts := range []*KeystoreOptions{
+		{KDF: KeystoreKDFScrypt, ScryptN: 1 << 10, Path: "m/12381/3600/0/0/0"},
+		{KDF: KeystoreKDFPBKDF2, PBKDF2C: 1 << 10},
+	} {
+		t.Run(opts.KDF, func(t *testing.T) {
+			data, err := EncryptKeystore(sk, "secret\u0007password", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// control codes are stripped from the password
+			got, err := DecryptKeystore(data, "secretpassword")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Serialize() != sk.Serialize() {
+				t.Fatal("decrypted secret key does not match")
+			}
+			if _, err := DecryptKeystore(data, "other"); err == nil {
+				t.Fatal("expected wrong password to fail")
+			}
+		})
+	}
+	if _, err := EncryptKeystore(sk, "", &KeystoreOptions{KDF: "argon2"}); err == nil {
+		t.Fatal("expected unsupported kdf to fail")
+	}
+}
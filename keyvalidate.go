@@ -0,0 +1,187 @@
+package blsu
+
+import (
+	"errors"
+	"fmt"
+	kbls "github.com/kilic/bls12-381"
+	"math/big"
+)
+
+// Reasons for a pubkey to be rejected by KeyValidate and the Pubkey deserialization,
+// wrapped with details. Use errors.Is to check the reason. See also ErrIdentityPubkey.
+var (
+	ErrPubkeyEncoding      = errors.New("invalid pubkey encoding")
+	ErrPubkeyNotOnCurve    = errors.New("pubkey is not on the curve")
+	ErrPubkeyNotInSubgroup = errors.New("pubkey is not in the prime-order subgroup")
+)
+
+// Reasons for a signature to be rejected by the Signature deserialization, compressed and uncompressed,
+// wrapped with details. Use errors.Is to check the reason.
+var (
+	ErrSignatureEncoding      = errors.New("invalid signature encoding")
+	ErrSignatureNotOnCurve    = errors.New("signature is not on the curve")
+	ErrSignatureNotInSubgroup = errors.New("signature is not in the prime-order subgroup")
+)
+
+// ErrSecretKey is the reason for a secret key to be rejected by the SecretKey deserialization and SkToPk.
+var ErrSecretKey = errors.New("invalid secret key")
+
+// pointErrors are the reasons to wrap, when rejecting the encoding of a pubkey or signature.
+// The same reasons apply to both groups: e.g. a PubkeyG2 is rejected with the pubkey errors.
+type pointErrors struct {
+	encoding      error
+	notOnCurve    error
+	notInSubgroup error
+}
+
+var (
+	pubkeyErrors    = pointErrors{ErrPubkeyEncoding, ErrPubkeyNotOnCurve, ErrPubkeyNotInSubgroup}
+	signatureErrors = pointErrors{ErrSignatureEncoding, ErrSignatureNotOnCurve, ErrSignatureNotInSubgroup}
+)
+
+// the base field modulus p of BLS12-381
+var fpModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+// isOnCurveX checks if x is the x-coordinate of a point on the G1 curve y**2 = x**3 + 4.
+func isOnCurveX(x *big.Int) bool {
+	rhs := new(big.Int).Exp(x, big.NewInt(3), fpModulus)
+	rhs.Add(rhs, big.NewInt(4))
+	rhs.Mod(rhs, fpModulus)
+	return rhs.Sign() == 0 || big.Jacobi(rhs, fpModulus) == 1
+}
+
+// isOnCurveX2 checks if x = c0 + c1*u is the x-coordinate of a point on the G2 curve y**2 = x**3 + 4*(1 + u),
+// with u**2 = -1. An element of Fp2 is a square if and only if its norm c0**2 + c1**2 is a square in Fp.
+func isOnCurveX2(c0, c1 *big.Int) bool {
+	// x**2 = (c0**2 - c1**2) + 2*c0*c1*u
+	a0 := new(big.Int).Mul(c0, c0)
+	a0.Sub(a0, new(big.Int).Mul(c1, c1))
+	a1 := new(big.Int).Mul(c0, c1)
+	a1.Lsh(a1, 1)
+	// x**3 + 4*(1 + u) = (a0*c0 - a1*c1 + 4) + (a0*c1 + a1*c0 + 4)*u
+	r0 := new(big.Int).Mul(a0, c0)
+	r0.Sub(r0, new(big.Int).Mul(a1, c1))
+	r0.Add(r0, big.NewInt(4))
+	r0.Mod(r0, fpModulus)
+	r1 := new(big.Int).Mul(a0, c1)
+	r1.Add(r1, new(big.Int).Mul(a1, c0))
+	r1.Add(r1, big.NewInt(4))
+	r1.Mod(r1, fpModulus)
+	norm := new(big.Int).Mul(r0, r0)
+	norm.Add(norm, new(big.Int).Mul(r1, r1))
+	norm.Mod(norm, fpModulus)
+	return norm.Sign() == 0 || big.Jacobi(norm, fpModulus) == 1
+}
+
+// checkCompressed validates the flag bits of a compressed point encoding, and that the x-coordinate is canonical.
+// It returns true if it is the encoding of the identity point, and the field elements of the x-coordinate otherwise,
+// in encoding order: x for G1, and c1, c0 for G2.
+// It is only used to classify the reason of a rejected encoding, the kilic decoding performs the same checks.
+func checkCompressed(in []byte) (infinity bool, x []*big.Int, err error) {
+	// The 3 most significant bits are flags:
+	// compression, infinity, and the sign of the y-coordinate
+	if in[0]&(1<<7) == 0 {
+		return false, nil, errors.New("compression flag must be set")
+	}
+	if in[0]&(1<<6) != 0 {
+		// the infinity encoding is unique: only the compression and infinity flags are set
+		if in[0] != 0xc0 {
+			return false, nil, fmt.Errorf("unexpected flags with infinity flag: %08b", in[0]>>5)
+		}
+		for i := 1; i < len(in); i++ {
+			if in[i] != 0 {
+				return false, nil, fmt.Errorf("non-zero byte %d with infinity flag", i)
+			}
+		}
+		return true, nil, nil
+	}
+	for i := 0; i < len(in); i += 48 {
+		var raw [48]byte
+		copy(raw[:], in[i:i+48])
+		if i == 0 {
+			raw[0] &= 0x1f
+		}
+		v := new(big.Int).SetBytes(raw[:])
+		if v.Cmp(fpModulus) >= 0 {
+			return false, nil, errors.New("x-coordinate is not smaller than the field modulus")
+		}
+		x = append(x, v)
+	}
+	return false, x, nil
+}
+
+// decodeG1 decodes a compressed G1 point, and checks that it is in the G1 subgroup.
+// The identity point is allowed. Errors wrap the given reasons.
+func decodeG1(in *[48]byte, errs pointErrors) (*kbls.PointG1, error) {
+	// includes the encoding, curve equation and sub-group checks
+	p, err := kbls.NewG1().FromCompressed(in[:])
+	if err == nil {
+		return p, nil
+	}
+	// The kilic errors are not typed: the reason is only computed on failure, to keep valid points fast.
+	infinity, x, encErr := checkCompressed(in[:])
+	if encErr != nil {
+		return nil, fmt.Errorf("%w: %v", errs.encoding, encErr)
+	}
+	// kilic accepts the same infinity encoding, this is not expected
+	if infinity {
+		return nil, fmt.Errorf("%w: %v", errs.encoding, err)
+	}
+	if !isOnCurveX(x[0]) {
+		return nil, fmt.Errorf("%w: %v", errs.notOnCurve, err)
+	}
+	return nil, fmt.Errorf("%w: %v", errs.notInSubgroup, err)
+}
+
+// decodeG2 decodes a compressed G2 point, and checks that it is in the G2 subgroup.
+// The identity point is allowed. Errors wrap the given reasons.
+func decodeG2(in *[96]byte, errs pointErrors) (*kbls.PointG2, error) {
+	// includes the encoding, curve equation and sub-group checks
+	p, err := kbls.NewG2().FromCompressed(in[:])
+	if err == nil {
+		return p, nil
+	}
+	// see decodeG1
+	infinity, x, encErr := checkCompressed(in[:])
+	if encErr != nil {
+		return nil, fmt.Errorf("%w: %v", errs.encoding, encErr)
+	}
+	if infinity {
+		return nil, fmt.Errorf("%w: %v", errs.encoding, err)
+	}
+	if !isOnCurveX2(x[1], x[0]) {
+		return nil, fmt.Errorf("%w: %v", errs.notOnCurve, err)
+	}
+	return nil, fmt.Errorf("%w: %v", errs.notInSubgroup, err)
+}
+
+// The KeyValidate algorithm ensures that a public key is valid.  In
+// particular, it ensures that a public key represents a valid, non-
+// identity point that is in the correct subgroup.
+//
+// The returned error wraps the reason of rejection:
+// ErrPubkeyEncoding, ErrPubkeyNotOnCurve, ErrPubkeyNotInSubgroup or ErrIdentityPubkey.
+func KeyValidate(pub [48]byte) error {
+	// 1. xP = pubkey_to_point(PK)
+	// 2. If xP is INVALID, return INVALID
+	// 3. If xP is the identity element, return INVALID
+	// 4. If pubkey_subgroup_check(xP) is INVALID, return INVALID
+	// 5. return VALID
+	// all part of the strict deserialization
+	var p Pubkey
+	return p.DeserializeStrict(&pub)
+}
+
+// DeserializeStrict deserializes a compressed point, and performs a full KeyValidate:
+// unlike Deserialize, the identity pubkey is rejected with ErrIdentityPubkey.
+func (pub *Pubkey) DeserializeStrict(in *[48]byte) error {
+	p, err := decodeG1(in, pubkeyErrors)
+	if err != nil {
+		return err
+	}
+	if (*kbls.G1)(nil).IsZero(p) {
+		return ErrIdentityPubkey
+	}
+	*pub = (Pubkey)(*p)
+	return nil
+}
@@ -0,0 +1,244 @@
+package blsu
+
+import (
+	"errors"
+	"fmt"
+	kbls "github.com/kilic/bls12-381"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// compressedX encodes x as compressed G1 point with the given 3 flag bits, without any validation.
+func compressedX(x *big.Int, flags byte) (out [48]byte) {
+	x.FillBytes(out[:])
+	out[0] |= flags << 5
+	return
+}
+
+// findX finds the smallest x >= start that is (or is not) the x-coordinate of a point on the curve.
+func findX(start int64, onCurve bool) *big.Int {
+	x := big.NewInt(start)
+	for isOnCurveX(x) != onCurve {
+		x.Add(x, big.NewInt(1))
+	}
+	return x
+}
+
+type keyValidateCase struct {
+	name string
+	in   [48]byte
+	// the expected error of the lenient deserialization, the identity is only rejected in strict mode
+	err error
+}
+
+func TestKeyValidate(t *testing.T) {
+	sk := randSK(t)
+	pub, err := SkToPk(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := pub.Serialize()
+	var generator [48]byte
+	copy(generator[:], kbls.NewG1().ToCompressed(&kbls.G1One))
+
+	var identity [48]byte
+	identity[0] = 0xc0
+
+	flipSign := valid
+	flipSign[0] ^= 0x20
+	noCompression := valid
+	noCompression[0] &^= 0x80
+	withInfinity := valid
+	withInfinity[0] |= 0x40
+
+	var infinityWithSign [48]byte
+	infinityWithSign[0] = 0xe0
+	var infinityLowBits [48]byte
+	infinityLowBits[0] = 0xc1
+
+	xValid := new(big.Int).SetBytes(generator[:])
+	xValid.SetBit(xValid, 383, 0)
+	xValid.SetBit(xValid, 382, 0)
+	xValid.SetBit(xValid, 381, 0)
+
+	pPlusX := new(big.Int).Add(fpModulus, findX(0, true))
+	allOnes := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 381), big.NewInt(1))
+
+	cases := []keyValidateCase{
+		{"random pubkey", valid, nil},
+		{"generator", generator, nil},
+		{"generator re-encoded", compressedX(xValid, 0b100), nil},
+		{"negated pubkey", flipSign, nil},
+		{"identity", identity, nil},
+		{"zero bytes", [48]byte{}, ErrPubkeyEncoding},
+		{"no compression flag", noCompression, ErrPubkeyEncoding},
+		{"infinity flag on pubkey", withInfinity, ErrPubkeyEncoding},
+		{"infinity with sign flag", infinityWithSign, ErrPubkeyEncoding},
+		{"infinity with low bits", infinityLowBits, ErrPubkeyEncoding},
+		{"x = p", compressedX(fpModulus, 0b100), ErrPubkeyEncoding},
+		{"x = p + 1", compressedX(new(big.Int).Add(fpModulus, big.NewInt(1)), 0b100), ErrPubkeyEncoding},
+		{"x = p + valid x", compressedX(pPlusX, 0b100), ErrPubkeyEncoding},
+		{"x = p + valid x, negated", compressedX(pPlusX, 0b101), ErrPubkeyEncoding},
+		{"x = 2**381 - 1", compressedX(allOnes, 0b100), ErrPubkeyEncoding},
+		{"not on curve", compressedX(findX(0, false), 0b100), ErrPubkeyNotOnCurve},
+		{"not on curve, negated", compressedX(findX(0, false), 0b101), ErrPubkeyNotOnCurve},
+		{"not on curve, large x", compressedX(findX(1<<62, false), 0b100), ErrPubkeyNotOnCurve},
+		{"not in subgroup", compressedX(findX(0, true), 0b100), ErrPubkeyNotInSubgroup},
+		{"not in subgroup, negated", compressedX(findX(0, true), 0b101), ErrPubkeyNotInSubgroup},
+		{"not in subgroup, large x", compressedX(findX(1<<62, true), 0b100), ErrPubkeyNotInSubgroup},
+	}
+	// all other combinations of flags on a valid x-coordinate are invalid
+	for _, flags := range []byte{0b000, 0b001, 0b010, 0b011, 0b110, 0b111} {
+		cases = append(cases, keyValidateCase{fmt.Sprintf("flags %03b", flags), compressedX(xValid, flags), ErrPubkeyEncoding})
+	}
+	// any non-zero byte after the infinity flag is invalid
+	for i := 1; i < 48; i++ {
+		in := identity
+		in[i] = 1
+		cases = append(cases, keyValidateCase{fmt.Sprintf("infinity with non-zero byte %d", i), in, ErrPubkeyEncoding})
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var lenient Pubkey
+			err := lenient.Deserialize(&c.in)
+			if c.err == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if out := lenient.Serialize(); out != c.in {
+					t.Fatalf("expected canonical encoding:\n%x\n%x", out[:], c.in[:])
+				}
+			} else if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %q, got: %v", c.err, err)
+			}
+
+			expectedStrict := c.err
+			if c.err == nil && c.in == identity {
+				expectedStrict = ErrIdentityPubkey
+			}
+			var strict Pubkey
+			err = strict.DeserializeStrict(&c.in)
+			if expectedStrict == nil {
+				if err != nil {
+					t.Fatalf("unexpected strict error: %v", err)
+				}
+			} else if !errors.Is(err, expectedStrict) {
+				t.Fatalf("expected strict error %q, got: %v", expectedStrict, err)
+			}
+			if err := KeyValidate(c.in); !errors.Is(err, expectedStrict) {
+				t.Fatalf("expected KeyValidate error %q, got: %v", expectedStrict, err)
+			}
+		})
+	}
+}
+
+// compressedX2 encodes x = c0 + c1*u as compressed G2 point with the given 3 flag bits, without any validation.
+func compressedX2(c0, c1 *big.Int, flags byte) (out [96]byte) {
+	c1.FillBytes(out[:48])
+	c0.FillBytes(out[48:])
+	out[0] |= flags << 5
+	return
+}
+
+// findX2 finds the smallest c0 >= start such that c0 + c1*u is (or is not) the x-coordinate of a point on the G2 curve.
+func findX2(start int64, c1 *big.Int, onCurve bool) *big.Int {
+	c0 := big.NewInt(start)
+	for isOnCurveX2(c0, c1) != onCurve {
+		c0.Add(c0, big.NewInt(1))
+	}
+	return c0
+}
+
+// TestDeserializeErrors checks the rejection reasons of the compressed Signature, PubkeyG2 and SignatureG1 deserialization,
+// like TestKeyValidate does for Pubkey.
+func TestDeserializeErrors(t *testing.T) {
+	sk := randSK(t)
+	sig := Sign(sk, []byte("deserialize errors")).Serialize()
+	pubG2 := skToPkG2(sk).Serialize()
+	sigG1 := SignG1(sk, []byte("deserialize errors")).Serialize()
+
+	noCompression := sig
+	noCompression[0] &^= 0x80
+	withInfinity := sig
+	withInfinity[0] |= 0x40
+	var identity [96]byte
+	identity[0] = 0xc0
+	infinityLowByte := identity
+	infinityLowByte[95] = 1
+
+	zero, one := big.NewInt(0), big.NewInt(1)
+	cases := []struct {
+		name string
+		in   [96]byte
+		// the expected reason, as one of the signature errors
+		err error
+	}{
+		{"valid", sig, nil},
+		{"identity", identity, nil},
+		{"zero bytes", [96]byte{}, ErrSignatureEncoding},
+		{"no compression flag", noCompression, ErrSignatureEncoding},
+		{"infinity flag on signature", withInfinity, ErrSignatureEncoding},
+		{"infinity with low byte", infinityLowByte, ErrSignatureEncoding},
+		{"c1 = p", compressedX2(zero, fpModulus, 0b100), ErrSignatureEncoding},
+		{"c0 = p", compressedX2(fpModulus, one, 0b100), ErrSignatureEncoding},
+		{"not on curve", compressedX2(findX2(0, zero, false), zero, 0b100), ErrSignatureNotOnCurve},
+		{"not on curve, c1 = 1", compressedX2(findX2(0, one, false), one, 0b101), ErrSignatureNotOnCurve},
+		{"not in subgroup", compressedX2(findX2(0, zero, true), zero, 0b100), ErrSignatureNotInSubgroup},
+		{"not in subgroup, c1 = 1", compressedX2(findX2(0, one, true), one, 0b101), ErrSignatureNotInSubgroup},
+	}
+	pubkeyErr := map[error]error{
+		ErrSignatureEncoding:      ErrPubkeyEncoding,
+		ErrSignatureNotOnCurve:    ErrPubkeyNotOnCurve,
+		ErrSignatureNotInSubgroup: ErrPubkeyNotInSubgroup,
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			in := c.in
+			if c.name == "valid" {
+				in = sig
+			}
+			var s Signature
+			err := s.Deserialize(&in)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected signature error %v, got: %v", c.err, err)
+			}
+			// cross-check the curve equation of isOnCurveX2 with the decompression of kilic
+			if c.err == ErrSignatureNotOnCurve && !strings.Contains(err.Error(), "not on curve") {
+				t.Fatalf("expected kilic to reject the point as not on the curve, got: %v", err)
+			}
+			if c.err == ErrSignatureNotInSubgroup && !strings.Contains(err.Error(), "subgroup") {
+				t.Fatalf("expected kilic to reject the point as not in the subgroup, got: %v", err)
+			}
+			if c.name == "valid" {
+				in = pubG2
+			}
+			var p PubkeyG2
+			if err := p.Deserialize(&in); !errors.Is(err, pubkeyErr[c.err]) {
+				t.Fatalf("expected pubkey error %v, got: %v", pubkeyErr[c.err], err)
+			}
+		})
+	}
+
+	// the G1 signatures share the G1 decoding with Pubkey, see TestKeyValidate
+	g1Cases := []struct {
+		name string
+		in   [48]byte
+		err  error
+	}{
+		{"valid", sigG1, nil},
+		{"zero bytes", [48]byte{}, ErrSignatureEncoding},
+		{"x = p", compressedX(fpModulus, 0b100), ErrSignatureEncoding},
+		{"not on curve", compressedX(findX(0, false), 0b100), ErrSignatureNotOnCurve},
+		{"not in subgroup", compressedX(findX(0, true), 0b100), ErrSignatureNotInSubgroup},
+	}
+	for _, c := range g1Cases {
+		t.Run("G1 "+c.name, func(t *testing.T) {
+			var s SignatureG1
+			if err := s.Deserialize(&c.in); !errors.Is(err, c.err) {
+				t.Fatalf("expected %v, got: %v", c.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package blsu
+
+import (
+	"crypto/rand"
+	"fmt"
+	kbls "github.com/kilic/bls12-381"
+)
+
+// Minimal-signature-size variant: signatures in G1, and pubkeys in G2.
+// This mirrors the minimal-pubkey-size functions (Pubkey in G1, Signature in G2) used in Eth2,
+// with the G1 hash-to-curve suite: BLS12381G1_XMD:SHA-256_SSWU_RO_
+
+var minSigDomain = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+
+var minSigAugDomain = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_AUG_")
+
+var minSigBasicDomain = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_")
+
+// proof-of-possession domain, used by hash_pubkey_to_point
+var minSigPopDomain = []byte("BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+
+type PubkeyG2 kbls.PointG2
+
+// Serialize to compressed point
+func (pub *PubkeyG2) Serialize() (out [96]byte) {
+	copy(out[:], kbls.NewG2().ToCompressed((*kbls.PointG2)(pub)))
+	return
+}
+
+// Deserialize compressed point.
+// Performs deserialization and a subgroup check, but like Pubkey the identity pubkey is allowed,
+// and rejected by the verify functions instead.
+// The returned error wraps the reason of rejection, like Pubkey: ErrPubkeyEncoding, ErrPubkeyNotOnCurve or ErrPubkeyNotInSubgroup.
+func (pub *PubkeyG2) Deserialize(in *[96]byte) error {
+	// includes sub-group check
+	p, err := decodeG2(in, pubkeyErrors)
+	if err != nil {
+		return err
+	}
+	*pub = (PubkeyG2)(*p)
+	return nil
+}
+
+type SignatureG1 kbls.PointG1
+
+// Serialize to compressed point
+func (sig *SignatureG1) Serialize() (out [48]byte) {
+	copy(out[:], kbls.NewG1().ToCompressed((*kbls.PointG1)(sig)))
+	return
+}
+
+// Deserialize compressed point.
+// The returned error wraps the reason of rejection, like Signature: ErrSignatureEncoding, ErrSignatureNotOnCurve or ErrSignatureNotInSubgroup.
+func (sig *SignatureG1) Deserialize(in *[48]byte) error {
+	// includes sub-group check
+	p, err := decodeG1(in, signatureErrors)
+	if err != nil {
+		return err
+	}
+	*sig = (SignatureG1)(*p)
+	return nil
+}
+
+// SkToPkG2 is SkToPk for the minimal-signature-size variant, with the public key in G2.
+func SkToPkG2(sk *SecretKey) (*PubkeyG2, error) {
+	// a secret integer such that 1 <= SK < r.
+	if ((*kbls.Fr)(sk)).IsZero() {
+		return nil, fmt.Errorf("%w: may not be zero", ErrSecretKey)
+	}
+	return skToPkG2(sk), nil
+}
+
+// skToPkG2 is SkToPkG2 without the zero secret key check.
+func skToPkG2(sk *SecretKey) *PubkeyG2 {
+	// 1. xP = SK * P
+	var xP kbls.PointG2
+	// constant-time, to not leak the secret key through timing, see ctMulG2
+	ctMulG2(kbls.NewG2(), &xP, &kbls.G2One, (*kbls.Fr)(sk))
+	// 2. PK = point_to_pubkey(xP)
+	// 3. return PK
+	return (*PubkeyG2)(&xP)
+}
+
+// coreSignG1 is coreSign with the signature in G1.
+func coreSignG1(sk *SecretKey, message []byte, dst []byte) *SignatureG1 {
+	g1 := kbls.NewG1()
+	// 1. Q = hash_to_point(message)
+	Q, err := g1.HashToCurve(message, dst)
+	if err != nil {
+		// only when the domain is too long, which we know it is not
+		panic(err)
+	}
+	// 2. R = SK * Q
+	// constant-time, to not leak the secret key through timing, see ctMulG1
+	var R kbls.PointG1
+	ctMulG1(g1, &R, Q, (*kbls.Fr)(sk))
+	// 3. signature = point_to_signature(R)
+	// 4. return signature
+	return (*SignatureG1)(&R)
+}
+
+// coreVerifyG1 is coreVerify with the signature in G1, and the pubkey in G2.
+// A nil error is returned if the signature is VALID.
+func coreVerifyG1(pk *PubkeyG2, message []byte, signature *SignatureG1, dst []byte) error {
+	// 1. R = signature_to_point(signature)
+	R := (*kbls.PointG1)(signature)
+	// 2. If R is INVALID, return INVALID
+	// 3. If signature_subgroup_check(R) is INVALID, return INVALID
+	// 4. If KeyValidate(PK) is INVALID, return INVALID
+	// steps 2-4 are part of deserialization, except the identity signature and pubkey checks
+	if (*kbls.G1)(nil).IsZero(R) {
+		return errIdentitySignature
+	}
+	// 5. xP = pubkey_to_point(PK)
+	xP := (*kbls.PointG2)(pk)
+	if (*kbls.G2)(nil).IsZero(xP) {
+		return ErrIdentityPubkey
+	}
+	// 6. Q = hash_to_point(message)
+	Q, err := kbls.NewG1().HashToCurve(message, dst)
+	if err != nil {
+		return fmt.Errorf("failed to hash message to G1: %v", err)
+	}
+	// 7. C1 = pairing(Q, xP)
+	eng := kbls.NewEngine()
+	eng.AddPair(Q, xP)
+	// 8. C2 = pairing(R, P)
+	eng.AddPairInv(R, &kbls.G2One)
+	// 9. If C1 == C2, return VALID, else return INVALID
+	if !eng.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// coreAggregateVerifyG1 is coreAggregateVerify with the signature in G1, and the pubkeys in G2.
+// A nil error is returned if the signature is VALID.
+func coreAggregateVerifyG1(pubkeys []*PubkeyG2, messages [][]byte, signature *SignatureG1, dst []byte) error {
+	// Precondition: n >= 1, otherwise return INVALID.
+	n := len(messages)
+	if n == 0 {
+		return fmt.Errorf("%w: need at least 1 message", ErrEmptyInput)
+	}
+	// implicit in spec: pubkeys and messages lengths must be equal
+	if len(pubkeys) != n {
+		return fmt.Errorf("%w: pubkeys: %d, messages: %d", ErrLengthMismatch, len(pubkeys), n)
+	}
+	// 1.  R = signature_to_point(signature)
+	R := (*kbls.PointG1)(signature)
+	// 2.  If R is INVALID, return INVALID
+	// 3.  If signature_subgroup_check(R) is INVALID, return INVALID
+	// 2 and 3 are part of the signature deserialization
+	// the identity signature is part of neither, and rejected like in coreVerify
+	if (*kbls.G1)(nil).IsZero(R) {
+		return errIdentitySignature
+	}
+
+	g1 := kbls.NewG1()
+	engine := kbls.NewEngine()
+	// 4.  C1 = 1 (the identity element in GT)
+	// 5.  for i in 1, ..., n:
+	for i := 0; i < n; i++ {
+		// 6. If KeyValidate(PK_i) is INVALID, return INVALID
+		// 7. xP = pubkey_to_point(PK_i)
+		xP := (*kbls.PointG2)(pubkeys[i])
+		// check identity pubkey
+		if (*kbls.G2)(nil).IsZero(xP) {
+			return &IndexError{Index: i, Err: ErrIdentityPubkey}
+		}
+		// 8. Q = hash_to_point(message_i)
+		Q, err := g1.HashToCurve(messages[i], dst)
+		if err != nil {
+			return &IndexError{Index: i, Err: fmt.Errorf("failed to hash message to G1: %v", err)}
+		}
+		// 9. C1 = C1 * pairing(Q, xP)
+		engine.AddPair(Q, xP)
+	}
+	// 10. C2 = pairing(R, P)
+	engine.AddPairInv(R, &kbls.G2One)
+	// 11. If C1 == C2, return VALID, else return INVALID
+	if !engine.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// AggregateG1 is Aggregate for signatures in G1.
+func AggregateG1(signatures []*SignatureG1) (*SignatureG1, error) {
+	// Precondition: n >= 1, otherwise return INVALID.
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: need at least 1 signature", ErrEmptyInput)
+	}
+	// 1. aggregate = signature_to_point(signature_1)
+	aggregate := (kbls.PointG1)(*signatures[0])
+	g1 := kbls.NewG1()
+	// 3. for i in 2, ..., n:
+	for i := 1; i < len(signatures); i++ {
+		// 6. aggregate = aggregate + next
+		g1.Add(&aggregate, &aggregate, (*kbls.PointG1)(signatures[i]))
+	}
+	// 7. signature = point_to_signature(aggregate)
+	// 8. return signature
+	return (*SignatureG1)(&aggregate), nil
+}
+
+// AggregatePubkeysG2 is AggregatePubkeys for pubkeys in G2.
+func AggregatePubkeysG2(pubkeys []*PubkeyG2) (*PubkeyG2, error) {
+	// Precondition: n >= 1, otherwise return INVALID.
+	if len(pubkeys) == 0 {
+		return nil, fmt.Errorf("%w: need at least 1 pubkey", ErrEmptyInput)
+	}
+	g2 := kbls.NewG2()
+	var aggregate kbls.PointG2
+	aggregate.Zero()
+	for i, pub := range pubkeys {
+		next := (*kbls.PointG2)(pub)
+		// check identity pubkey
+		if (*kbls.G2)(nil).IsZero(next) {
+			return nil, &IndexError{Index: i, Err: ErrIdentityPubkey}
+		}
+		g2.Add(&aggregate, &aggregate, next)
+	}
+	return (*PubkeyG2)(&aggregate), nil
+}
+
+// CiphersuiteG1 is the minimal-signature-size equivalent of Ciphersuite: signatures in G1, pubkeys in G2.
+type CiphersuiteG1 struct {
+	suite
+}
+
+// PopCiphersuiteG1 is BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_, the default minimal-signature-size ciphersuite.
+var PopCiphersuiteG1 = &CiphersuiteG1{suite{scheme: SchemePOP, dst: minSigDomain, popDST: minSigPopDomain}}
+
+// AugCiphersuiteG1 is BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_AUG_
+var AugCiphersuiteG1 = &CiphersuiteG1{suite{scheme: SchemeAug, dst: minSigAugDomain}}
+
+// BasicCiphersuiteG1 is BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_
+var BasicCiphersuiteG1 = &CiphersuiteG1{suite{scheme: SchemeBasic, dst: minSigBasicDomain}}
+
+// NewCiphersuiteG1 creates a minimal-signature-size ciphersuite with a custom DST, see NewCiphersuite.
+func NewCiphersuiteG1(scheme Scheme, dst []byte, popDST []byte) (*CiphersuiteG1, error) {
+	s, err := newSuite(scheme, dst, popDST)
+	if err != nil {
+		return nil, err
+	}
+	return &CiphersuiteG1{s}, nil
+}
+
+// message returns the message as signed in the scheme of the ciphersuite, see Ciphersuite.
+func (cs *CiphersuiteG1) message(pk *PubkeyG2, message []byte) []byte {
+	return cs.augMessage(func() []byte {
+		pkRaw := pk.Serialize()
+		return pkRaw[:]
+	}, message)
+}
+
+// Sign computes a signature in G1 from SK, a secret key, and message, an octet string.
+//
+// A zero secret key results in the identity signature, see Ciphersuite.Sign.
+func (cs *CiphersuiteG1) Sign(sk *SecretKey, message []byte) *SignatureG1 {
+	if cs.scheme == SchemeAug {
+		return coreSignG1(sk, cs.message(skToPkG2(sk), message), cs.dst)
+	}
+	return coreSignG1(sk, message, cs.dst)
+}
+
+// Verify checks a signature over a message under the public key PK.
+func (cs *CiphersuiteG1) Verify(pk *PubkeyG2, message []byte, signature *SignatureG1) bool {
+	return cs.VerifyDetailed(pk, message, signature) == nil
+}
+
+// VerifyDetailed is Verify, but returns why the signature is INVALID, e.g. ErrIdentityPubkey or ErrInvalidSignature.
+func (cs *CiphersuiteG1) VerifyDetailed(pk *PubkeyG2, message []byte, signature *SignatureG1) error {
+	return coreVerifyG1(pk, cs.message(pk, message), signature, cs.dst)
+}
+
+// AggregateVerify checks an aggregated signature over several (PK, message) pairs.
+//
+// In the Basic scheme all messages must be distinct.
+// In the Aug scheme each message is prefixed with its pubkey.
+func (cs *CiphersuiteG1) AggregateVerify(pubkeys []*PubkeyG2, messages [][]byte, signature *SignatureG1) bool {
+	return cs.AggregateVerifyDetailed(pubkeys, messages, signature) == nil
+}
+
+// AggregateVerifyDetailed is AggregateVerify, but returns why the signature is INVALID.
+// An *IndexError is returned for an invalid pubkey or message.
+func (cs *CiphersuiteG1) AggregateVerifyDetailed(pubkeys []*PubkeyG2, messages [][]byte, signature *SignatureG1) error {
+	messages, err := cs.aggregateMessages(len(pubkeys), messages, func(i int) []byte {
+		return cs.message(pubkeys[i], messages[i])
+	})
+	if err != nil {
+		return err
+	}
+	return coreAggregateVerifyG1(pubkeys, messages, signature, cs.dst)
+}
+
+// FastAggregateVerify verifies the aggregate of multiple signatures on the same message.
+//
+// This function applies only to the Proof Of Possession signature scheme, and returns INVALID for other schemes.
+func (cs *CiphersuiteG1) FastAggregateVerify(pubkeys []*PubkeyG2, message []byte, signature *SignatureG1) bool {
+	return cs.FastAggregateVerifyDetailed(pubkeys, message, signature) == nil
+}
+
+// FastAggregateVerifyDetailed is FastAggregateVerify, but returns why the signature is INVALID.
+// An *IndexError is returned for an invalid pubkey.
+func (cs *CiphersuiteG1) FastAggregateVerifyDetailed(pubkeys []*PubkeyG2, message []byte, signature *SignatureG1) error {
+	if err := cs.checkFastAggregate(); err != nil {
+		return err
+	}
+	// 1. aggregate = pubkey_to_point(PK_1)
+	// 2. for i in 2, ..., n:
+	// 3. next = pubkey_to_point(PK_i)
+	// 4. aggregate = aggregate + next
+	// 5. PK = point_to_pubkey(aggregate)
+	PK, err := AggregatePubkeysG2(pubkeys)
+	if err != nil {
+		return err
+	}
+	// 6. return coreVerify(PK, message, signature)
+	return coreVerifyG1(PK, message, signature, cs.dst)
+}
+
+// PopProve generates a proof of possession for the public key corresponding to secret key SK.
+//
+// This function applies only to the Proof Of Possession signature scheme, and returns an error for other schemes.
+func (cs *CiphersuiteG1) PopProve(sk *SecretKey) (*SignatureG1, error) {
+	if err := cs.checkPop(); err != nil {
+		return nil, err
+	}
+	// 1. PK = SkToPk(SK)
+	// a zero secret key is not checked, and results in the identity proof, see Sign
+	pk := skToPkG2(sk)
+	// 2. Q = hash_pubkey_to_point(PK)
+	// 3. R = SK * Q
+	// 4. proof = point_to_signature(R)
+	// 5. return proof
+	pkRaw := pk.Serialize()
+	return coreSignG1(sk, pkRaw[:], cs.popDST), nil
+}
+
+// PopVerify checks whether proof is valid for PK.
+//
+// This function applies only to the Proof Of Possession signature scheme, and returns INVALID for other schemes.
+func (cs *CiphersuiteG1) PopVerify(pk *PubkeyG2, proof *SignatureG1) bool {
+	if cs.checkPop() != nil {
+		return false
+	}
+	pkRaw := pk.Serialize()
+	return coreVerifyG1(pk, pkRaw[:], proof, cs.popDST) == nil
+}
+
+// SignatureSetVerify verifies (pubkey,message,signature) tuples as a single batch, see the package-level SignatureSetVerify.
+//
+// Messages and signatures are in G1, so the random-scalar multiplications are cheap,
+// and the batch is verified in the calling goroutine.
+func (cs *CiphersuiteG1) SignatureSetVerify(pubkeys []*PubkeyG2, messages [][]byte, signatures []*SignatureG1) (bool, error) {
+	n := len(pubkeys)
+	if len(messages) != n || len(signatures) != n {
+		return false, fmt.Errorf("%w: pubs: %d, msgs: %d, sigs: %d", ErrLengthMismatch, n, len(messages), len(signatures))
+	}
+	if n == 0 {
+		return true, nil
+	}
+	rngBuf := make([]byte, n*64, n*64)
+	// the first entry does not need randomness
+	if _, err := rand.Read(rngBuf[64:]); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrEntropy, err)
+	}
+	g1 := kbls.NewG1()
+	eng := kbls.NewEngine()
+	var aggSig, tmpSig kbls.PointG1
+	aggSig.Zero()
+	var randScalar kbls.Fr
+	for i := 0; i < n; i++ {
+		pub := (*kbls.PointG2)(pubkeys[i])
+		// the identity pubkey and signature are INVALID, see checkSigSetIdentity
+		if (*kbls.G2)(nil).IsZero(pub) || (*kbls.G1)(nil).IsZero((*kbls.PointG1)(signatures[i])) {
+			return false, nil
+		}
+		// error only occurs on invalid domain length
+		msg, _ := g1.HashToCurve(cs.message(pubkeys[i], messages[i]), cs.dst)
+		tmpSig = *(*kbls.PointG1)(signatures[i])
+		// Optimization: the first signature and message entry are not multiplied with a random scalar
+		if i != 0 {
+			randScalar.FromBytes(rngBuf[i*64 : (i+1)*64])
+			g1.MulScalar(&tmpSig, &tmpSig, &randScalar)
+			g1.MulScalar(msg, msg, &randScalar)
+		}
+		g1.Add(&aggSig, &aggSig, &tmpSig)
+		eng.AddPair(msg, pub)
+	}
+	eng.AddPairInv(&aggSig, &kbls.G2One)
+	return eng.Check(), nil
+}
+
+// The package-level G1 functions use the default PopCiphersuiteG1.
+
+// SignG1 is Sign, with the signature in G1.
+func SignG1(sk *SecretKey, message []byte) *SignatureG1 {
+	return PopCiphersuiteG1.Sign(sk, message)
+}
+
+// VerifyG1 is Verify, with the signature in G1 and the pubkey in G2.
+func VerifyG1(pk *PubkeyG2, message []byte, signature *SignatureG1) bool {
+	return PopCiphersuiteG1.Verify(pk, message, signature)
+}
+
+// VerifyDetailedG1 is VerifyDetailed, with the signature in G1 and the pubkey in G2.
+func VerifyDetailedG1(pk *PubkeyG2, message []byte, signature *SignatureG1) error {
+	return PopCiphersuiteG1.VerifyDetailed(pk, message, signature)
+}
+
+// AggregateVerifyG1 is AggregateVerify, with the signature in G1 and the pubkeys in G2.
+func AggregateVerifyG1(pubkeys []*PubkeyG2, messages [][]byte, signature *SignatureG1) bool {
+	return PopCiphersuiteG1.AggregateVerify(pubkeys, messages, signature)
+}
+
+// AggregateVerifyDetailedG1 is AggregateVerifyDetailed, with the signature in G1 and the pubkeys in G2.
+func AggregateVerifyDetailedG1(pubkeys []*PubkeyG2, messages [][]byte, signature *SignatureG1) error {
+	return PopCiphersuiteG1.AggregateVerifyDetailed(pubkeys, messages, signature)
+}
+
+// FastAggregateVerifyG1 is FastAggregateVerify, with the signature in G1 and the pubkeys in G2.
+func FastAggregateVerifyG1(pubkeys []*PubkeyG2, message []byte, signature *SignatureG1) bool {
+	return PopCiphersuiteG1.FastAggregateVerify(pubkeys, message, signature)
+}
+
+// FastAggregateVerifyDetailedG1 is FastAggregateVerifyDetailed, with the signature in G1 and the pubkeys in G2.
+func FastAggregateVerifyDetailedG1(pubkeys []*PubkeyG2, message []byte, signature *SignatureG1) error {
+	return PopCiphersuiteG1.FastAggregateVerifyDetailed(pubkeys, message, signature)
+}
+
+// PopProveG1 is PopProve, with the proof in G1.
+func PopProveG1(sk *SecretKey) *SignatureG1 {
+	// error only occurs in other schemes than the POP scheme
+	proof, _ := PopCiphersuiteG1.PopProve(sk)
+	return proof
+}
+
+// PopVerifyG1 is PopVerify, with the proof in G1 and the pubkey in G2.
+func PopVerifyG1(pk *PubkeyG2, proof *SignatureG1) bool {
+	return PopCiphersuiteG1.PopVerify(pk, proof)
+}
+
+// SignatureSetVerifyG1 is SignatureSetVerify, with the signatures in G1 and the pubkeys in G2.
+func SignatureSetVerifyG1(pubkeys []*PubkeyG2, messages [][]byte, signatures []*SignatureG1) (bool, error) {
+	return PopCiphersuiteG1.SignatureSetVerify(pubkeys, messages, signatures)
+}
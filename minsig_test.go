@@ -0,0 +1,356 @@
+package blsu
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func prepareMinSigTest(t testing.TB, cs *CiphersuiteG1, n int) ([]*SecretKey, []*PubkeyG2, [][]byte, []*SignatureG1) {
+	sks := make([]*SecretKey, n, n)
+	pubs := make([]*PubkeyG2, n, n)
+	msgs := make([][]byte, n, n)
+	sigs := make([]*SignatureG1, n, n)
+	for i := 0; i < n; i++ {
+		sks[i] = randSK(t)
+		pub, err := SkToPkG2(sks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs[i] = pub
+		msgs[i] = []byte{byte(i), 0x42}
+		sigs[i] = cs.Sign(sks[i], msgs[i])
+	}
+	return sks, pubs, msgs, sigs
+}
+
+func TestMinSigSerialization(t *testing.T) {
+	sk := randSK(t)
+	pub, err := SkToPkG2(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := SignG1(sk, []byte("hello"))
+
+	pubRaw := pub.Serialize()
+	var pub2 PubkeyG2
+	if err := pub2.Deserialize(&pubRaw); err != nil {
+		t.Fatal(err)
+	}
+	if pub2.Serialize() != pubRaw {
+		t.Fatal("pubkey roundtrip failed")
+	}
+	sigRaw := sig.Serialize()
+	var sig2 SignatureG1
+	if err := sig2.Deserialize(&sigRaw); err != nil {
+		t.Fatal(err)
+	}
+	if sig2.Serialize() != sigRaw {
+		t.Fatal("signature roundtrip failed")
+	}
+	// x = 1 is not on the G2 curve
+	var wrongPub PubkeyG2
+	var badPub [96]byte
+	badPub[0] = 0x80
+	badPub[95] = 1
+	if err := wrongPub.Deserialize(&badPub); err == nil {
+		t.Fatal("expected invalid pubkey to fail")
+	}
+	var zero SecretKey
+	if _, err := SkToPkG2(&zero); err == nil {
+		t.Fatal("expected zero secret key to fail")
+	}
+}
+
+func TestMinSigSignVerify(t *testing.T) {
+	for _, cs := range []*CiphersuiteG1{PopCiphersuiteG1, AugCiphersuiteG1, BasicCiphersuiteG1} {
+		t.Run(cs.Scheme().String(), func(t *testing.T) {
+			_, pubs, msgs, sigs := prepareMinSigTest(t, cs, 4)
+			for i := range pubs {
+				if !cs.Verify(pubs[i], msgs[i], sigs[i]) {
+					t.Fatalf("expected valid signature %d", i)
+				}
+				if cs.Verify(pubs[i], []byte("other"), sigs[i]) {
+					t.Fatalf("expected invalid signature %d for other message", i)
+				}
+				if cs.Verify(pubs[(i+1)%len(pubs)], msgs[i], sigs[i]) {
+					t.Fatalf("expected invalid signature %d for other pubkey", i)
+				}
+			}
+			aggSig, err := AggregateG1(sigs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cs.AggregateVerify(pubs, msgs, aggSig) {
+				t.Fatal("expected valid aggregate signature")
+			}
+			if cs.AggregateVerify(pubs[1:], msgs[1:], aggSig) {
+				t.Fatal("expected invalid aggregate signature for subset")
+			}
+			if valid, err := cs.SignatureSetVerify(pubs, msgs, sigs); err != nil || !valid {
+				t.Fatalf("expected valid signature set: %v", err)
+			}
+			sigs[2] = sigs[1]
+			if valid, err := cs.SignatureSetVerify(pubs, msgs, sigs); err != nil || valid {
+				t.Fatalf("expected invalid signature set: %v", err)
+			}
+		})
+	}
+}
+
+func TestMinSigSuiteSeparation(t *testing.T) {
+	sk := randSK(t)
+	pub, err := SkToPkG2(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("separate")
+	if BasicCiphersuiteG1.Verify(pub, msg, PopCiphersuiteG1.Sign(sk, msg)) {
+		t.Fatal("POP signature must not be valid in the Basic scheme")
+	}
+	if AugCiphersuiteG1.Verify(pub, msg, BasicCiphersuiteG1.Sign(sk, msg)) {
+		t.Fatal("Basic signature must not be valid in the Aug scheme")
+	}
+	custom, err := NewCiphersuiteG1(SchemeBasic, []byte("TESTNET_G1_NUL_"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !custom.Verify(pub, msg, custom.Sign(sk, msg)) {
+		t.Fatal("expected valid signature with custom DST")
+	}
+	if BasicCiphersuiteG1.Verify(pub, msg, custom.Sign(sk, msg)) {
+		t.Fatal("custom DST signature must not be valid with the default DST")
+	}
+	if _, err := NewCiphersuiteG1(SchemePOP, []byte("TESTNET_"), []byte("TESTNET_")); err == nil {
+		t.Fatal("expected equal DSTs to fail")
+	}
+}
+
+func TestMinSigBasicDistinctMessages(t *testing.T) {
+	sks, pubs, _, _ := prepareMinSigTest(t, BasicCiphersuiteG1, 2)
+	msg := []byte("duplicate")
+	msgs := [][]byte{msg, msg}
+	aggSig := mustAggregateG1(t, BasicCiphersuiteG1.Sign(sks[0], msg), BasicCiphersuiteG1.Sign(sks[1], msg))
+	if BasicCiphersuiteG1.AggregateVerify(pubs, msgs, aggSig) {
+		t.Fatal("expected duplicate messages to be rejected")
+	}
+	aggSig = mustAggregateG1(t, PopCiphersuiteG1.Sign(sks[0], msg), PopCiphersuiteG1.Sign(sks[1], msg))
+	if !PopCiphersuiteG1.AggregateVerify(pubs, msgs, aggSig) {
+		t.Fatal("expected duplicate messages to be accepted in the POP scheme")
+	}
+}
+
+func mustAggregateG1(t testing.TB, sigs ...*SignatureG1) *SignatureG1 {
+	out, err := AggregateG1(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMinSigPop(t *testing.T) {
+	sks, pubs, _, _ := prepareMinSigTest(t, PopCiphersuiteG1, 3)
+	msg := []byte("same message")
+	sigs := make([]*SignatureG1, len(sks), len(sks))
+	for i, sk := range sks {
+		proof := PopProveG1(sk)
+		if !PopVerifyG1(pubs[i], proof) {
+			t.Fatalf("expected valid proof %d", i)
+		}
+		if PopVerifyG1(pubs[(i+1)%len(pubs)], proof) {
+			t.Fatalf("expected invalid proof %d for other pubkey", i)
+		}
+		pubRaw := pubs[i].Serialize()
+		if VerifyG1(pubs[i], pubRaw[:], proof) {
+			t.Fatalf("proof %d must not be valid as signature", i)
+		}
+		sigs[i] = SignG1(sk, msg)
+	}
+	aggSig := mustAggregateG1(t, sigs...)
+	if !FastAggregateVerifyG1(pubs, msg, aggSig) {
+		t.Fatal("expected valid fast aggregate signature")
+	}
+	if FastAggregateVerifyG1(pubs[1:], msg, aggSig) {
+		t.Fatal("expected invalid fast aggregate signature for subset")
+	}
+	if FastAggregateVerifyG1(nil, msg, aggSig) {
+		t.Fatal("expected empty pubkeys to fail")
+	}
+	if _, err := BasicCiphersuiteG1.PopProve(sks[0]); err == nil {
+		t.Fatal("expected PopProve to fail in the Basic scheme")
+	}
+	if AugCiphersuiteG1.FastAggregateVerify(pubs, msg, aggSig) {
+		t.Fatal("expected FastAggregateVerify to fail in the Aug scheme")
+	}
+}
+
+func TestMinSigIdentityPubkey(t *testing.T) {
+	sk := randSK(t)
+	msg := []byte("identity")
+	sig := SignG1(sk, msg)
+	var identity PubkeyG2
+	var raw [96]byte
+	raw[0] = 0xc0
+	if err := identity.Deserialize(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if VerifyG1(&identity, msg, sig) {
+		t.Fatal("expected identity pubkey to fail")
+	}
+	if AggregateVerifyG1([]*PubkeyG2{&identity}, [][]byte{msg}, sig) {
+		t.Fatal("expected identity pubkey to fail in aggregate")
+	}
+	valid, err := SignatureSetVerifyG1([]*PubkeyG2{&identity}, [][]byte{msg}, []*SignatureG1{sig})
+	if valid || err != nil {
+		t.Fatalf("expected identity pubkey to fail in signature set, without error: %v", err)
+	}
+	var identitySig SignatureG1
+	identitySigRaw := [48]byte{0xc0}
+	if err := identitySig.Deserialize(&identitySigRaw); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := SkToPkG2(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err = SignatureSetVerifyG1([]*PubkeyG2{pub, &identity}, [][]byte{msg, msg}, []*SignatureG1{sig, &identitySig})
+	if valid || err != nil {
+		t.Fatalf("expected identity tuple to fail in signature set, without error: %v", err)
+	}
+	valid, err = SignatureSetVerifyG1([]*PubkeyG2{pub, pub}, [][]byte{msg, msg}, []*SignatureG1{sig, &identitySig})
+	if valid || err != nil {
+		t.Fatalf("expected identity signature to fail in signature set, without error: %v", err)
+	}
+	if _, err := SignatureSetVerifyG1([]*PubkeyG2{&identity}, nil, nil); err == nil {
+		t.Fatal("expected length mismatch to fail")
+	}
+}
+
+func TestMinSigDetailedErrors(t *testing.T) {
+	_, pubs, msgs, sigs := prepareMinSigTest(t, PopCiphersuiteG1, 3)
+	var identityPub PubkeyG2
+	identityPubRaw := [96]byte{0xc0}
+	if err := identityPub.Deserialize(&identityPubRaw); err != nil {
+		t.Fatal(err)
+	}
+	var identitySig SignatureG1
+	identitySigRaw := [48]byte{0xc0}
+	if err := identitySig.Deserialize(&identitySigRaw); err != nil {
+		t.Fatal(err)
+	}
+	aggSig := mustAggregateG1(t, sigs...)
+
+	if err := VerifyDetailedG1(pubs[0], msgs[0], sigs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyDetailedG1(pubs[0], msgs[1], sigs[0]); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got: %v", err)
+	}
+	if err := VerifyDetailedG1(&identityPub, msgs[0], sigs[0]); !errors.Is(err, ErrIdentityPubkey) {
+		t.Fatalf("expected identity pubkey, got: %v", err)
+	}
+	// a zero secret key signs with the identity signature, which verifies under the identity pubkey in the pairing,
+	// and must be rejected before that.
+	if err := VerifyDetailedG1(&identityPub, msgs[0], &identitySig); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected identity signature to be invalid, got: %v", err)
+	}
+	if VerifyG1(&identityPub, msgs[0], &identitySig) {
+		t.Fatal("expected identity signature to fail")
+	}
+
+	if err := AggregateVerifyDetailedG1(pubs, msgs, aggSig); err != nil {
+		t.Fatal(err)
+	}
+	if err := AggregateVerifyDetailedG1(nil, nil, aggSig); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected empty input, got: %v", err)
+	}
+	if err := AggregateVerifyDetailedG1(pubs[:2], msgs, aggSig); !errors.Is(err, ErrLengthMismatch) {
+		t.Fatalf("expected length mismatch, got: %v", err)
+	}
+	withIdentity := []*PubkeyG2{pubs[0], &identityPub, pubs[2]}
+	expectIndexError(t, AggregateVerifyDetailedG1(withIdentity, msgs, aggSig), 1, ErrIdentityPubkey)
+	expectIndexError(t, BasicCiphersuiteG1.AggregateVerifyDetailed(pubs[:2], [][]byte{msgs[0], msgs[0]}, aggSig), 1, ErrDuplicateMessage)
+
+	same := []byte("same message")
+	sameSig := mustAggregateG1(t, SignG1(randSK(t), same))
+	if err := FastAggregateVerifyDetailedG1(nil, same, sameSig); !errors.Is(err, ErrEmptyInput) {
+		t.Fatalf("expected empty input, got: %v", err)
+	}
+	expectIndexError(t, FastAggregateVerifyDetailedG1(withIdentity, same, sameSig), 1, ErrIdentityPubkey)
+	if err := FastAggregateVerifyDetailedG1(pubs, same, sameSig); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got: %v", err)
+	}
+}
+
+// TestMinSigKnownAnswer checks interop of the G1 signatures and G2 pubkeys against known answers.
+// The messages are the RFC 9380 BLS12381G1_XMD:SHA-256_SSWU_RO_ hash-to-curve vectors, with the same DST:
+// with the secret key 1 the signature is the hashed point of the RFC, with another key the pubkey and signature are multiples of the generator and hashed point,
+// computed with an independent implementation.
+func TestMinSigKnownAnswer(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_")
+	cs, err := NewCiphersuiteG1(SchemeBasic, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := hex32("0000000000000000000000000000000000000000000000000000000000000001")
+	other := hex32("263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+	testCases := []struct {
+		name   string
+		secret [32]byte
+		msg    string
+		pubkey string
+		sig    string
+	}{
+		{
+			name:   "one_empty",
+			secret: one,
+			msg:    "",
+			// the compressed G2 generator
+			pubkey: "93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8",
+			sig:    "852926add2207b76ca4fa57a8734416c8dc95e24501772c814278700eed6d1e4e8cf62d9c09db0fac349612b759e79a1",
+		},
+		{
+			name:   "one_abc",
+			secret: one,
+			msg:    "abc",
+			sig:    "83567bc5ef9c690c2ab2ecdf6a96ef1c139cc0b2f284dca0a9a7943388a49a3aee664ba5379a7655d3c68900be2f6903",
+		},
+		{
+			name:   "other_empty",
+			secret: other,
+			msg:    "",
+			pubkey: "ac400b70f6f8cd35648f5c126cce5417f3be4d8eefbd42ceb4286a14df7e03135313fe5845e3a575faab3e8b949d248814856c22d8cdb2967c720e963eedc999e738373b14172f06fc915769d3cc5ab7ae0a1b9c38f48b5585fb09d4bd2733bb",
+			sig:    "ac4c15edfe12389dea5cf2f5467f03f9ca708d494e00b571ee1a0ebc4c524a855c53c2605cde7f5118edb2127573137a",
+		},
+		{
+			name:   "other_abc",
+			secret: other,
+			msg:    "abc",
+			sig:    "93f9785e8c254986c8ec5270c655dd09546548058544a04b20592c876bd22339c24a60ba78e3c313ad592ce2853da852",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sk SecretKey
+			if err := sk.Deserialize(&tc.secret); err != nil {
+				t.Fatal(err)
+			}
+			pub, err := SkToPkG2(&sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubRaw := pub.Serialize()
+			if tc.pubkey != "" && hex.EncodeToString(pubRaw[:]) != tc.pubkey {
+				t.Fatalf("unexpected pubkey: %x", pubRaw[:])
+			}
+			sig := cs.Sign(&sk, []byte(tc.msg))
+			sigRaw := sig.Serialize()
+			if got := hex.EncodeToString(sigRaw[:]); got != tc.sig {
+				t.Fatalf("unexpected signature: %s", got)
+			}
+			if !cs.Verify(pub, []byte(tc.msg), sig) {
+				t.Fatal("expected known answer signature to verify")
+			}
+		})
+	}
+}
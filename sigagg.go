@@ -1,8 +1,8 @@
 package blsu
 
 import (
+	"context"
 	"crypto/rand"
-	"errors"
 	"fmt"
 	kbls "github.com/kilic/bls12-381"
 	"sync"
@@ -26,23 +26,37 @@ type DeferBLS interface {
 	Check() error
 }
 
+// DeferBLSContext is a DeferBLS that can stop the deferred check when a context is cancelled.
+// The DeferBLS implementations of this package implement it, use a type assertion to check.
+type DeferBLSContext interface {
+	DeferBLS
+	// CheckContext is Check, but stops the pairing work when the context is cancelled,
+	// and then returns the context error.
+	CheckContext(ctx context.Context) error
+}
+
 type aggregateCheck struct {
 	sync.Mutex
-	eng    *kbls.Engine
+	cs *Ciphersuite
+	// pairs are kept until Check, instead of a pairing engine, to split the pairing products for cancellation
+	pairs  []sigSetPair
 	aggSig *kbls.PointG2
 	// scratchpads
-	g1 *kbls.G1
 	g2 *kbls.G2
 }
 
 // NewAggregateCheck returns a signature-set that implements DeferBLS
 func NewAggregateCheck() DeferBLS {
+	return PopCiphersuite.NewAggregateCheck()
+}
+
+// NewAggregateCheck returns a signature-set that implements DeferBLS, verifying signatures of the ciphersuite.
+func (cs *Ciphersuite) NewAggregateCheck() DeferBLS {
 	var aggSig kbls.PointG2
 	aggSig.Zero()
 	return &aggregateCheck{
-		eng:    kbls.NewEngine(),
+		cs:     cs,
 		aggSig: &aggSig,
-		g1:     kbls.NewG1(),
 		g2:     kbls.NewG2(),
 	}
 }
@@ -51,11 +65,11 @@ func (a *aggregateCheck) coreAggregateVerify(pubkeys []*Pubkey, messages [][]byt
 	// Precondition: n >= 1, otherwise return INVALID.
 	n := uint64(len(messages))
 	if n == 0 {
-		return errors.New("coreAggregateVerify: Precondition: n >= 1, otherwise return INVALID")
+		return fmt.Errorf("%w: need at least 1 message", ErrEmptyInput)
 	}
 	// implicit in spec: pubkeys and messages lengths must be equal
 	if uint64(len(pubkeys)) != n {
-		return errors.New("coreAggregateVerify: pubkeys and messages lengths must be equal")
+		return fmt.Errorf("%w: pubkeys: %d, messages: %d", ErrLengthMismatch, len(pubkeys), n)
 	}
 
 	// 1.  R = signature_to_point(signature)
@@ -63,11 +77,15 @@ func (a *aggregateCheck) coreAggregateVerify(pubkeys []*Pubkey, messages [][]byt
 	// 2.  If R is INVALID, return INVALID
 	// 3.  If signature_subgroup_check(R) is INVALID, return INVALID
 	// 2 and 3 are part of the signature deserialization
+	// the identity signature is part of neither, and rejected like in coreVerify
+	if (*kbls.G2)(nil).IsZero(R) {
+		return errIdentitySignature
+	}
 
 	var randScalar kbls.Fr
 	_, err := randScalar.Rand(rand.Reader)
 	if err != nil {
-		return errors.New("failed to get random scalar for aggregateCheck.coreAggregateVerify")
+		return fmt.Errorf("%w: random scalar for aggregateCheck.coreAggregateVerify: %v", ErrEntropy, err)
 	}
 
 	// 4.  C1 = 1 (the identity element in GT)
@@ -81,19 +99,19 @@ func (a *aggregateCheck) coreAggregateVerify(pubkeys []*Pubkey, messages [][]byt
 		xP := (*kbls.PointG1)(pubkeys[i])
 		// check identity pubkey
 		if (*kbls.G1)(nil).IsZero(xP) {
-			return fmt.Errorf("identity pubkey (%d)", i)
+			return &IndexError{Index: int(i), Err: ErrIdentityPubkey}
 		}
 		// 8. Q = hash_to_point(message_i)
-		Q, err := a.g2.HashToCurve(messages[i], domain)
+		Q, err := a.g2.HashToCurve(messages[i], a.cs.dst)
 		if err != nil {
 			// e.g. when the domain is too long. Maybe change to panic if never due to a usage error?
-			return fmt.Errorf("fail to hash message to g2: %v", err)
+			return &IndexError{Index: int(i), Err: fmt.Errorf("failed to hash message to G2: %v", err)}
 		}
 
 		// 9. C1 = C1 * pairing(Q, xP)
 		// aggregateCheck change: mul msg with the rand scalar
 		a.g2.MulScalar(Q, Q, &randScalar)
-		a.eng.AddPair(xP, Q)
+		a.pairs = append(a.pairs, sigSetPair{pub: *xP, msg: Q})
 	}
 	// 10. C2 = pairing(R, P)
 	// aggregateCheck change: mul sig with the rand scalar, and aggregate to defer the pairing till Check()
@@ -109,6 +127,14 @@ func (a *aggregateCheck) coreAggregateVerify(pubkeys []*Pubkey, messages [][]byt
 func (a *aggregateCheck) AggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) error {
 	a.Lock()
 	defer a.Unlock()
+	// If any two input messages are equal in the Basic scheme, return INVALID.
+	// mprime_i = PK_i || message_i in the Aug scheme.
+	messages, err := a.cs.aggregateMessages(len(pubkeys), messages, func(i int) []byte {
+		return a.cs.message(pubkeys[i], messages[i])
+	})
+	if err != nil {
+		return err
+	}
 	return a.coreAggregateVerify(pubkeys, messages, signature)
 }
 
@@ -122,27 +148,30 @@ func (a *aggregateCheck) coreVerify(pk *Pubkey, message []byte, signature *Signa
 	if (*kbls.G2)(nil).IsZero(R) {
 		// KeyValidate is assumed through deserialization of Pubkey and Signature,
 		// but the identity pubkey/signature case is not part of that, thus verify here.
-		return errors.New("coreVerify: pubkey cannot be identity pubkey")
+		return errIdentitySignature
 	}
 
 	// 5. xP = pubkey_to_point(PK)
 	xP := (*kbls.PointG1)(pk)
+	if (*kbls.G1)(nil).IsZero(xP) {
+		return ErrIdentityPubkey
+	}
 	// 6. Q = hash_to_point(message)
-	Q, err := a.g2.HashToCurve(message, domain)
+	Q, err := a.g2.HashToCurve(message, a.cs.dst)
 	if err != nil {
 		// e.g. when the domain is too long. Maybe change to panic if never due to a usage error?
-		return fmt.Errorf("coreVerify: failed to hash message to g2: %v", err)
+		return fmt.Errorf("failed to hash message to G2: %v", err)
 	}
 	var randScalar kbls.Fr
 	_, err = randScalar.Rand(rand.Reader)
 	if err != nil {
-		return errors.New("failed to get random scalar for aggregateCheck.coreVerify")
+		return fmt.Errorf("%w: random scalar for aggregateCheck.coreVerify: %v", ErrEntropy, err)
 	}
 
 	// 7. C1 = pairing(Q, xP)
 	// aggregateCheck change: mul msg with the rand scalar
 	a.g2.MulScalar(Q, Q, &randScalar)
-	a.eng.AddPair(xP, Q)
+	a.pairs = append(a.pairs, sigSetPair{pub: *xP, msg: Q})
 	// 8. C2 = pairing(R, P)
 	// aggregateCheck change: mul sig with the rand scalar, and aggregate to defer the pairing till Check()
 	var Rcpy kbls.PointG2
@@ -157,38 +186,24 @@ func (a *aggregateCheck) coreVerify(pk *Pubkey, message []byte, signature *Signa
 func (a *aggregateCheck) Verify(pk *Pubkey, message []byte, signature *Signature) error {
 	a.Lock()
 	defer a.Unlock()
-	return a.coreVerify(pk, message, signature)
+	return a.coreVerify(pk, a.cs.message(pk, message), signature)
 }
 
 func (a *aggregateCheck) FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) error {
+	if err := a.cs.checkFastAggregate(); err != nil {
+		return err
+	}
 	a.Lock()
 	defer a.Unlock()
-	// Precondition: n >= 1, otherwise return INVALID.
-	n := uint64(len(pubkeys))
-	if n == 0 {
-		return errors.New("pubkeys list cannot be empty")
-	}
-	// Procedure:
 	// 1. aggregate = pubkey_to_point(PK_1)
-	// copy the first pubkey
-	aggregate := *(*kbls.PointG1)(pubkeys[0])
-	// check identity pubkey
-	if (*kbls.G1)(nil).IsZero(&aggregate) {
-		return fmt.Errorf("pubkey 0 cannot be zero")
-	}
 	// 2. for i in 2, ..., n:
-	for i := uint64(1); i < n; i++ {
-		// 3. next = pubkey_to_point(PK_i)
-		next := (*kbls.PointG1)(pubkeys[i])
-		// check identity pubkey
-		if (*kbls.G1)(nil).IsZero(next) {
-			return fmt.Errorf("pubkey %d cannot be zero", i)
-		}
-		// 4. aggregate = aggregate + next
-		a.g1.Add(&aggregate, &aggregate, next)
-	}
+	// 3. next = pubkey_to_point(PK_i)
+	// 4. aggregate = aggregate + next
 	// 5. PK = point_to_pubkey(aggregate)
-	PK := (*Pubkey)(&aggregate)
+	PK, err := AggregatePubkeys(pubkeys)
+	if err != nil {
+		return err
+	}
 	// 6. return coreVerify(PK, message, signature)
 	return a.coreVerify(PK, message, signature)
 }
@@ -203,58 +218,60 @@ func (a *aggregateCheck) Eth2FastAggregateVerify(pubkeys []*Pubkey, message []by
 
 // Check will reset the AggregateCheck after determining the result
 func (a *aggregateCheck) Check() error {
+	return a.CheckContext(context.Background())
+}
+
+// CheckContext will reset the AggregateCheck after determining the result.
+// If the context is cancelled first, the context error is returned,
+// and the deferred checks are kept, to retry or discard with a later Check.
+// The pairings are computed in the calling goroutine, in products of at most cancellablePairs pairs,
+// and the context is checked before each product.
+func (a *aggregateCheck) CheckContext(ctx context.Context) error {
 	a.Lock()
 	defer a.Unlock()
-	a.eng.AddPairInv(&kbls.G1One, a.aggSig)
-	res := a.eng.Check()
-	a.eng.Reset()
+	res, err := checkPairings(ctx, a.pairs, a.aggSig, &SignatureSetOptions{SingleThreaded: true})
+	if err != nil {
+		return err
+	}
+	a.pairs = nil
 	a.aggSig.Zero()
 	if res {
 		return nil
 	} else {
-		return errors.New("invalid aggregate signature")
+		return fmt.Errorf("%w: deferred aggregate signature check failed", ErrInvalidSignature)
 	}
 }
 
-var _ DeferBLS = (*aggregateCheck)(nil)
+var _ DeferBLSContext = (*aggregateCheck)(nil)
 
 // ImmediateCheck implements DeferBLS without deferring anything, i.e. signature checks will be performed immediately.
 type ImmediateCheck struct{}
 
 func (i ImmediateCheck) AggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) error {
-	if AggregateVerify(pubkeys, messages, signature) {
-		return nil
-	} else {
-		return errors.New("invalid signature")
-	}
+	return AggregateVerifyDetailed(pubkeys, messages, signature)
 }
 
 func (i ImmediateCheck) Verify(pk *Pubkey, message []byte, signature *Signature) error {
-	if Verify(pk, message, signature) {
-		return nil
-	} else {
-		return errors.New("invalid signature")
-	}
+	return VerifyDetailed(pk, message, signature)
 }
 
 func (i ImmediateCheck) FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) error {
-	if FastAggregateVerify(pubkeys, message, signature) {
-		return nil
-	} else {
-		return errors.New("invalid signature")
-	}
+	return FastAggregateVerifyDetailed(pubkeys, message, signature)
 }
 
 func (i ImmediateCheck) Eth2FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) error {
-	if Eth2FastAggregateVerify(pubkeys, message, signature) {
+	if len(pubkeys) == 0 && (*kbls.G2)(nil).IsZero((*kbls.PointG2)(signature)) {
 		return nil
-	} else {
-		return errors.New("invalid signature")
 	}
+	return FastAggregateVerifyDetailed(pubkeys, message, signature)
 }
 
 func (i ImmediateCheck) Check() error {
 	return nil
 }
 
-var _ DeferBLS = (*ImmediateCheck)(nil)
+func (i ImmediateCheck) CheckContext(ctx context.Context) error {
+	return nil
+}
+
+var _ DeferBLSContext = (*ImmediateCheck)(nil)
@@ -1,6 +1,8 @@
 package blsu
 
 import (
+	"context"
+	"errors"
 	kbls "github.com/kilic/bls12-381"
 	"testing"
 )
@@ -189,3 +191,53 @@ func TestDeferEth2FastAggregateVerify(t *testing.T) {
 		}
 	})
 }
+
+func TestDeferBLSCheckContext(t *testing.T) {
+	// more pairs than a single cancellable pairing product
+	n := cancellablePairs*2 + 3
+	pubs, msgs, sigs := prepareSignatureSetTest(t, n)
+	check, ok := NewAggregateCheck().(DeferBLSContext)
+	if !ok {
+		t.Fatal("expected the aggregate check to implement DeferBLSContext")
+	}
+	for i := 0; i < n; i++ {
+		if err := check.Verify(pubs[i], msgs[i], sigs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := check.CheckContext(cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+	// cancel during the pairings: the cancellation checks are between the pairing products, and before the last product
+	counting := newCountingContext(2)
+	if err := check.CheckContext(counting); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation during the pairings, got: %v", err)
+	}
+	if calls := counting.count(); calls != 2 {
+		t.Fatalf("expected the pairings to stop at check 2, got %d checks", calls)
+	}
+	// the deferred checks are kept after cancellation, and checked with a cancellable context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := check.CheckContext(ctx); err != nil {
+		t.Fatalf("expected valid deferred checks: %v", err)
+	}
+
+	// the last signature is of another message
+	sigs[n-1] = sigs[0]
+	for i := 0; i < n; i++ {
+		if err := check.Verify(pubs[i], msgs[i], sigs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := check.CheckContext(ctx); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected invalid deferred checks, got: %v", err)
+	}
+
+	var immediate DeferBLS = ImmediateCheck{}
+	if err := immediate.(DeferBLSContext).CheckContext(cancelled); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,7 +1,7 @@
 package blsu
 
 import (
-	"errors"
+	"fmt"
 	kbls "github.com/kilic/bls12-381"
 )
 
@@ -11,6 +11,13 @@ import (
 
 var domain = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
 
+var augDomain = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_AUG_")
+
+var basicDomain = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_")
+
+// proof-of-possession domain, used by hash_pubkey_to_point, see below.
+var popDomain = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
 // cipher-suite: BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_
 // BLS_SIG_
 // BLS12381G2_XMD:SHA-256_SSWU_RO  # hash to curve suite, G2, SHA-256
@@ -48,10 +55,12 @@ func (pub *Pubkey) Serialize() (out [48]byte) {
 // Deserialize compressed point.
 // Performs deserialization, a subgroup check, but not a full KeyValidate: the identity pubkey is allowed.
 // Functions that are specified to perform a KeyValidate on a Pubkey can ignore it, after deserializing a valid *Pubkey,
-// EXCEPT the identity pubkey check.
+// EXCEPT the identity pubkey check. See DeserializeStrict for a full KeyValidate.
+//
+// The returned error wraps the reason of rejection, see KeyValidate.
 func (pub *Pubkey) Deserialize(in *[48]byte) error {
-	// includes sub-group check (TODO: is this check correct and complete though?)
-	p, err := kbls.NewG1().FromCompressed(in[:])
+	// includes sub-group check
+	p, err := decodeG1(in, pubkeyErrors)
 	if err != nil {
 		return err
 	}
@@ -68,10 +77,11 @@ func (sig *Signature) Serialize() (out [96]byte) {
 	return
 }
 
-// Deserialize compressed point
+// Deserialize compressed point.
+// The returned error wraps the reason of rejection: ErrSignatureEncoding, ErrSignatureNotOnCurve or ErrSignatureNotInSubgroup.
 func (sig *Signature) Deserialize(in *[96]byte) error {
 	// includes sub-group check
-	p, err := kbls.NewG2().FromCompressed(in[:])
+	p, err := decodeG2(in, signatureErrors)
 	if err != nil {
 		return err
 	}
@@ -89,11 +99,16 @@ func (sk *SecretKey) Serialize() (out [32]byte) {
 }
 
 // Deserialize big-endian serialized integer. A modulo r is applied to out-of-range keys.
+// A zero secret key is rejected with ErrSecretKey.
 func (sk *SecretKey) Deserialize(in *[32]byte) error {
 	(*kbls.Fr)(sk).FromBytes(in[:])
+	// kilic only reduces inputs larger than r: r itself is left as is, but is zero
+	if [4]uint64(*sk) == frModulus {
+		(*kbls.Fr)(sk).Zero()
+	}
 	// KeyGen states: a uniformly random integer such that 1 <= SK < r.
 	if ((*kbls.Fr)(sk)).IsZero() {
-		return errors.New("secret key may not be zero")
+		return fmt.Errorf("%w: may not be zero", ErrSecretKey)
 	}
 	return nil
 }
@@ -102,45 +117,39 @@ func (sk *SecretKey) Deserialize(in *[32]byte) error {
 func SkToPk(sk *SecretKey) (*Pubkey, error) {
 	// a secret integer such that 1 <= SK < r.
 	if ((*kbls.Fr)(sk)).IsZero() {
-		return nil, errors.New("secret key may not be zero")
+		return nil, fmt.Errorf("%w: may not be zero", ErrSecretKey)
 	}
 
+	return skToPk(sk), nil
+}
+
+// skToPk is SkToPk without the zero secret key check: a zero secret key results in the identity pubkey.
+func skToPk(sk *SecretKey) *Pubkey {
 	// 1. xP = SK * P
 	var xP kbls.PointG1
 	g1 := kbls.NewG1()
-	g1.MulScalar(&xP, &kbls.G1One, (*kbls.Fr)(sk))
+	// constant-time, to not leak the secret key through timing, see ctMulG1
+	ctMulG1(g1, &xP, &kbls.G1One, (*kbls.Fr)(sk))
 	// 2. PK = point_to_pubkey(xP)
 	PK := (*Pubkey)(&xP)
 	// 3. return PK
-	return PK, nil
+	return PK
 }
 
-// TODO: unsupported, should be part of bytes->Pubkey deserialization
-//
-//// The KeyValidate algorithm ensures that a public key is valid.  In
-//// particular, it ensures that a public key represents a valid, non-
-//// identity point that is in the correct subgroup.
-//func KeyValidate(pub []byte) bool {
-//	// 1. xP = pubkey_to_point(PK)
-//	// 2. If xP is INVALID, return INVALID
-//	// 3. If xP is the identity element, return INVALID
-//	// 4. If pubkey_subgroup_check(xP) is INVALID, return INVALID
-//	// 5. return VALID
-//	return false
-//}
-
 // The coreSign algorithm computes a signature from SK, a secret key, and message, an octet string.
-func coreSign(sk *SecretKey, message []byte) *Signature {
+// The dst is the hash-to-curve domain separation tag of the ciphersuite.
+func coreSign(sk *SecretKey, message []byte, dst []byte) *Signature {
 	g2 := kbls.NewG2()
 	// 1. Q = hash_to_point(message)
-	Q, err := g2.HashToCurve(message, domain)
+	Q, err := g2.HashToCurve(message, dst)
 	if err != nil {
 		// only when the domain is too long, which we know it is not
 		panic(err)
 	}
 	// 2. R = SK * Q
+	// constant-time, to not leak the secret key through timing, see ctMulG2
 	var R kbls.PointG2
-	g2.MulScalar(&R, Q, (*kbls.Fr)(sk))
+	ctMulG2(g2, &R, Q, (*kbls.Fr)(sk))
 	// 3. signature = point_to_signature(R)
 	// serialization is deferred, see Signature.Serialize()
 	signature := (*Signature)(&R)
@@ -149,7 +158,9 @@ func coreSign(sk *SecretKey, message []byte) *Signature {
 }
 
 // The coreVerify algorithm checks that a signature is valid for the octet string message under the public key PK.
-func coreVerify(pk *Pubkey, message []byte, signature *Signature) bool {
+// The dst is the hash-to-curve domain separation tag of the ciphersuite.
+// A nil error is returned if the signature is VALID.
+func coreVerify(pk *Pubkey, message []byte, signature *Signature, dst []byte) error {
 	// 1. R = signature_to_point(signature)
 	R := (*kbls.PointG2)(signature)
 	// 2. If R is INVALID, return INVALID
@@ -159,16 +170,19 @@ func coreVerify(pk *Pubkey, message []byte, signature *Signature) bool {
 	if (*kbls.G2)(nil).IsZero(R) {
 		// KeyValidate is assumed through deserialization of Pubkey and Signature,
 		// but the identity pubkey/signature case is not part of that, thus verify here.
-		return false
+		return errIdentitySignature
 	}
 
 	// 5. xP = pubkey_to_point(PK)
 	xP := (*kbls.PointG1)(pk)
+	if (*kbls.G1)(nil).IsZero(xP) {
+		return ErrIdentityPubkey
+	}
 	// 6. Q = hash_to_point(message)
-	Q, err := kbls.NewG2().HashToCurve(message, domain)
+	Q, err := kbls.NewG2().HashToCurve(message, dst)
 	if err != nil {
 		// e.g. when the domain is too long. Maybe change to panic if never due to a usage error?
-		return false
+		return fmt.Errorf("failed to hash message to G2: %v", err)
 	}
 	// 7. C1 = pairing(Q, xP)
 	eng := kbls.NewEngine()
@@ -177,14 +191,17 @@ func coreVerify(pk *Pubkey, message []byte, signature *Signature) bool {
 	P := &kbls.G1One
 	eng.AddPairInv(P, R) // inverse, optimization to mul with inverse and check equality to 1
 	// 9. If C1 == C2, return VALID, else return INVALID
-	return eng.Check()
+	if !eng.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
 }
 
 // The Aggregate algorithm aggregates multiple signatures into one.
 func Aggregate(signatures []*Signature) (*Signature, error) {
 	// Precondition: n >= 1, otherwise return INVALID.
 	if len(signatures) == 0 {
-		return nil, errors.New("need at least 1 signature")
+		return nil, fmt.Errorf("%w: need at least 1 signature", ErrEmptyInput)
 	}
 
 	// 1. aggregate = signature_to_point(signature_1)
@@ -210,15 +227,17 @@ func Aggregate(signatures []*Signature) (*Signature, error) {
 }
 
 // The coreAggregateVerify algorithm checks an aggregated signature over several (PK, message) pairs.
-func coreAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) bool {
+// The dst is the hash-to-curve domain separation tag of the ciphersuite.
+// A nil error is returned if the signature is VALID.
+func coreAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature, dst []byte) error {
 	// Precondition: n >= 1, otherwise return INVALID.
 	n := uint64(len(messages))
 	if n == 0 {
-		return false
+		return fmt.Errorf("%w: need at least 1 message", ErrEmptyInput)
 	}
 	// implicit in spec: pubkeys and messages lengths must be equal
 	if uint64(len(pubkeys)) != n {
-		return false
+		return fmt.Errorf("%w: pubkeys: %d, messages: %d", ErrLengthMismatch, len(pubkeys), n)
 	}
 
 	// 1.  R = signature_to_point(signature)
@@ -226,6 +245,10 @@ func coreAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signat
 	// 2.  If R is INVALID, return INVALID
 	// 3.  If signature_subgroup_check(R) is INVALID, return INVALID
 	// 2 and 3 are part of the signature deserialization
+	// the identity signature is part of neither, and rejected like in coreVerify
+	if (*kbls.G2)(nil).IsZero(R) {
+		return errIdentitySignature
+	}
 
 	g2 := kbls.NewG2()
 	engine := kbls.NewEngine()
@@ -240,13 +263,13 @@ func coreAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signat
 		xP := (*kbls.PointG1)(pubkeys[i])
 		// check identity pubkey
 		if (*kbls.G1)(nil).IsZero(xP) {
-			return false
+			return &IndexError{Index: int(i), Err: ErrIdentityPubkey}
 		}
 		// 8. Q = hash_to_point(message_i)
-		Q, err := g2.HashToCurve(messages[i], domain)
+		Q, err := g2.HashToCurve(messages[i], dst)
 		if err != nil {
 			// e.g. when the domain is too long. Maybe change to panic if never due to a usage error?
-			return false
+			return &IndexError{Index: int(i), Err: fmt.Errorf("failed to hash message to G2: %v", err)}
 		}
 
 		// 9. C1 = C1 * pairing(Q, xP)
@@ -256,101 +279,127 @@ func coreAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signat
 	P := &kbls.G1One
 	engine.AddPairInv(P, R)
 	// 11. If C1 == C2, return VALID, else return INVALID
-	return engine.Check()
+	if !engine.Check() {
+		return ErrInvalidSignature
+	}
+	return nil
 }
 
 // In the Proof Of Possession scheme
 // The Sign, Verify, and AggregateVerify functions are identical to coreSign, coreVerify, and coreAggregateVerify (Section 2), respectively.
+// The package-level functions use the default PopCiphersuite, see Ciphersuite for other ciphersuites.
 
 // The AggregateVerify algorithm checks an aggregated signature over several (PK, message) pairs.
 func AggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) bool {
-	return coreAggregateVerify(pubkeys, messages, signature)
+	return PopCiphersuite.AggregateVerify(pubkeys, messages, signature)
+}
+
+// AggregateVerifyDetailed is AggregateVerify, but returns why the signature is INVALID.
+// An *IndexError is returned for an invalid pubkey or message.
+func AggregateVerifyDetailed(pubkeys []*Pubkey, messages [][]byte, signature *Signature) error {
+	return PopCiphersuite.AggregateVerifyDetailed(pubkeys, messages, signature)
 }
 
 // The Verify algorithm checks an aggregated signature over several (PK, message) pairs.
 func Verify(pk *Pubkey, message []byte, signature *Signature) bool {
-	return coreVerify(pk, message, signature)
+	return PopCiphersuite.Verify(pk, message, signature)
+}
+
+// VerifyDetailed is Verify, but returns why the signature is INVALID, e.g. ErrIdentityPubkey or ErrInvalidSignature.
+func VerifyDetailed(pk *Pubkey, message []byte, signature *Signature) error {
+	return PopCiphersuite.VerifyDetailed(pk, message, signature)
 }
 
 // The Sign algorithm computes a signature from SK, a secret key, and message, an octet string.
+// A zero secret key results in the identity signature, see Ciphersuite.Sign.
 func Sign(sk *SecretKey, message []byte) *Signature {
-	return coreSign(sk, message)
+	return PopCiphersuite.Sign(sk, message)
 }
 
-// TODO: do we need the basic-scheme version of AggregateVerify?
-//
-//// The AggregateVerify function first ensures that all messages are distinct, and then invokes coreAggregateVerify.
-////
-//// This function only applies to the Basic signature scheme (Proof Of Possession uses coreAggregateVerify directly)
-//func AggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) bool {
-//	// Precondition: n >= 1, otherwise return INVALID.
-//	n := uint64(len(messages))
-//	if n == 0 {
-//		return false
-//	}
+// The PopProve algorithm generates a proof of possession for the public key corresponding to secret key SK.
+// A zero secret key results in the identity proof, see Ciphersuite.Sign.
 //
-//	// 1. If any two input messages are equal, return INVALID.
-//
-//	// Sort first, then check if any adjacent messages are equal to spot duplicates
-//	sorted := make([][]byte, len(messages), len(messages))
-//	copy(sorted, messages)
-//	sort.Slice(sorted, func(i, j int) bool {
-//		return bytes.Compare(sorted[i], sorted[j]) < 0
-//	})
-//	for i, j := uint64(0), uint64(1); j < n; i, j = i+1, j+1 {
-//		if bytes.Compare(sorted[i], sorted[j]) == 0 {
-//			return false
-//		}
-//	}
+// This function applies only to the Proof Of Possession signature scheme, see Ciphersuite.PopProve for other ciphersuites.
+func PopProve(sk *SecretKey) *Signature {
+	// error only occurs in other schemes than the POP scheme
+	proof, _ := PopCiphersuite.PopProve(sk)
+	return proof
+}
+
+// The PopVerify algorithm checks whether proof is valid for PK.
+// Registries should check the proof of possession of each pubkey before using it in FastAggregateVerify,
+// to prevent rogue-key attacks.
 //
-//	// 2. return coreAggregateVerify((PK_1, ..., PK_n),
-//	//                               (message_1, ..., message_n),
-//	//                               signature)
-//	return coreAggregateVerify(pubkeys, messages, signature)
-//}
+// This function applies only to the Proof Of Possession signature scheme.
+func PopVerify(pk *Pubkey, proof *Signature) bool {
+	return PopCiphersuite.PopVerify(pk, proof)
+}
 
 // FastAggregateVerify is a verification algorithm for the aggregate of multiple signatures on the same message.
 // This function is faster than AggregateVerify.
 //
 // This function applies only to the Proof Of Possession signature scheme.
 func FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) bool {
-	// Precondition: n >= 1, otherwise return INVALID.
-	n := uint64(len(pubkeys))
-	if n == 0 {
-		return false
-	}
+	return PopCiphersuite.FastAggregateVerify(pubkeys, message, signature)
+}
 
-	g1 := kbls.NewG1()
-	// Procedure:
-	// 1. aggregate = pubkey_to_point(PK_1)
-	// copy the first pubkey
-	aggregate := *(*kbls.PointG1)(pubkeys[0])
-	// check identity pubkey
-	if (*kbls.G1)(nil).IsZero(&aggregate) {
-		return false
-	}
-	// 2. for i in 2, ..., n:
-	for i := uint64(1); i < n; i++ {
-		// 3. next = pubkey_to_point(PK_i)
-		next := (*kbls.PointG1)(pubkeys[i])
-		// check identity pubkey
-		if (*kbls.G1)(nil).IsZero(next) {
-			return false
-		}
-		// 4. aggregate = aggregate + next
-		g1.Add(&aggregate, &aggregate, next)
-	}
-	// 5. PK = point_to_pubkey(aggregate)
-	PK := (*Pubkey)(&aggregate)
-	// 6. return coreVerify(PK, message, signature)
-	return coreVerify(PK, message, signature)
+// FastAggregateVerifyDetailed is FastAggregateVerify, but returns why the signature is INVALID.
+// An *IndexError is returned for an invalid pubkey.
+func FastAggregateVerifyDetailed(pubkeys []*Pubkey, message []byte, signature *Signature) error {
+	return PopCiphersuite.FastAggregateVerifyDetailed(pubkeys, message, signature)
+}
+
+// In the Message Augmentation scheme
+// Signatures are computed over the serialized public key concatenated with the message,
+// using a distinct ciphersuite: BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_AUG_
+
+// The AugSign algorithm computes a signature from SK, a secret key, and message, an octet string,
+// in the Message Augmentation scheme.
+// A zero secret key results in the identity signature, see Ciphersuite.Sign.
+func AugSign(sk *SecretKey, message []byte) *Signature {
+	return AugCiphersuite.Sign(sk, message)
+}
+
+// The AugVerify algorithm checks a signature in the Message Augmentation scheme.
+func AugVerify(pk *Pubkey, message []byte, signature *Signature) bool {
+	return AugCiphersuite.Verify(pk, message, signature)
+}
+
+// The AugAggregateVerify algorithm checks an aggregated signature over several (PK, message) pairs,
+// in the Message Augmentation scheme.
+func AugAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) bool {
+	return AugCiphersuite.AggregateVerify(pubkeys, messages, signature)
+}
+
+// In the Basic scheme
+// The Sign and Verify functions are identical to coreSign and coreVerify,
+// using a distinct ciphersuite: BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_
+// AggregateVerify requires all messages to be distinct, to prevent rogue-key attacks.
+
+// The BasicSign algorithm computes a signature from SK, a secret key, and message, an octet string,
+// in the Basic scheme.
+// A zero secret key results in the identity signature, see Ciphersuite.Sign.
+func BasicSign(sk *SecretKey, message []byte) *Signature {
+	return BasicCiphersuite.Sign(sk, message)
+}
+
+// The BasicVerify algorithm checks a signature in the Basic scheme.
+func BasicVerify(pk *Pubkey, message []byte, signature *Signature) bool {
+	return BasicCiphersuite.Verify(pk, message, signature)
+}
+
+// The BasicAggregateVerify function first ensures that all messages are distinct, and then invokes coreAggregateVerify.
+//
+// This function only applies to the Basic signature scheme (Proof Of Possession uses coreAggregateVerify directly)
+func BasicAggregateVerify(pubkeys []*Pubkey, messages [][]byte, signature *Signature) bool {
+	return BasicCiphersuite.AggregateVerify(pubkeys, messages, signature)
 }
 
 // AggregatePubkeys is specified as `eth2_aggregate_pubkeys` in Eth2, and is the G1 variant of Aggregate in G2.
 func AggregatePubkeys(pubkeys []*Pubkey) (*Pubkey, error) {
 	// Precondition: n >= 1, otherwise return INVALID.
 	if len(pubkeys) == 0 {
-		return nil, errors.New("need at least 1 pubkey")
+		return nil, fmt.Errorf("%w: need at least 1 pubkey", ErrEmptyInput)
 	}
 
 	// 1. aggregate = pubkey_to_point(pubkey_1)
@@ -362,7 +411,7 @@ func AggregatePubkeys(pubkeys []*Pubkey) (*Pubkey, error) {
 	// check identity pubkey
 	// see https://github.com/ethereum/consensus-specs/issues/2538
 	if (*kbls.G1)(nil).IsZero(&aggregate) {
-		return nil, errors.New("cannot add zero pubkey to aggregate")
+		return nil, &IndexError{Index: 0, Err: ErrIdentityPubkey}
 	}
 
 	g1 := kbls.NewG1()
@@ -372,7 +421,7 @@ func AggregatePubkeys(pubkeys []*Pubkey) (*Pubkey, error) {
 		next := (*kbls.PointG1)(pubkeys[i])
 		// check identity pubkey
 		if (*kbls.G1)(nil).IsZero(next) {
-			return nil, errors.New("cannot add zero pubkey to aggregate")
+			return nil, &IndexError{Index: i, Err: ErrIdentityPubkey}
 		}
 		// 5. If next is INVALID, return INVALID
 		// part of the Pubkey deserialization
@@ -387,16 +436,5 @@ func AggregatePubkeys(pubkeys []*Pubkey) (*Pubkey, error) {
 
 // Wrapper to FastAggregateVerify accepting the G2_POINT_AT_INFINITY signature when pubkeys is empty.
 func Eth2FastAggregateVerify(pubkeys []*Pubkey, message []byte, signature *Signature) bool {
-	// if len(pubkeys) == 0 and signature == G2_POINT_AT_INFINITY: return True
-
-	// G2_POINT_AT_INFINITY(serialized form is b'\xc0' + b'\x00' * 95, i.e. top 2 bits are one.
-	// most significant bit: to indicate it's compressed (if it were serialized)
-	// second most signficant bit: to indicate it is at infinity, the rest of the bits must be zero then.
-
-	// The IsZero method does not actually use the G2 scratchpad,
-	// so we call into this method with a nil-receiver to avoid unnecessary work.
-	if len(pubkeys) == 0 && (*kbls.G2)(nil).IsZero((*kbls.PointG2)(signature)) {
-		return true
-	}
-	return FastAggregateVerify(pubkeys, message, signature)
+	return PopCiphersuite.Eth2FastAggregateVerify(pubkeys, message, signature)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	kbls "github.com/kilic/bls12-381"
 	"io/fs"
@@ -67,11 +68,74 @@ func TestHashToG2(t *testing.T) {
 }
 
 func TestSecretKey_Deserialize(t *testing.T) {
-	// TODO TestSecretKey_Deserialize
+	for _, c := range []struct {
+		name string
+		in   [32]byte
+		err  error
+	}{
+		{"one", hex32("0000000000000000000000000000000000000000000000000000000000000001"), nil},
+		{"r - 1", hex32("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000000"), nil},
+		{"zero", [32]byte{}, ErrSecretKey},
+		// a modulo r is applied, r itself is zero
+		{"r", hex32("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001"), ErrSecretKey},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var sk SecretKey
+			if err := sk.Deserialize(&c.in); !errors.Is(err, c.err) {
+				t.Fatalf("expected %v, got: %v", c.err, err)
+			}
+		})
+	}
+	var zero SecretKey
+	if _, err := SkToPk(&zero); !errors.Is(err, ErrSecretKey) {
+		t.Fatalf("expected SkToPk to reject a zero key, got: %v", err)
+	}
+	if _, err := SkToPkG2(&zero); !errors.Is(err, ErrSecretKey) {
+		t.Fatalf("expected SkToPkG2 to reject a zero key, got: %v", err)
+	}
 }
 
 func TestSecretKey_Serialize(t *testing.T) {
-	// TODO TestSecretKey_Serialize
+	for _, c := range []struct {
+		name string
+		in   [32]byte
+		out  [32]byte
+	}{
+		{"one", hex32("0000000000000000000000000000000000000000000000000000000000000001"), hex32("0000000000000000000000000000000000000000000000000000000000000001")},
+		{"r - 1", hex32("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000000"), hex32("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000000")},
+		{"test key", hex32(keystoreTestSecret), hex32(keystoreTestSecret)},
+		// keys larger than r are reduced modulo r
+		{"r + 1", hex32("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000002"), hex32("0000000000000000000000000000000000000000000000000000000000000001")},
+		{"max", hex32("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), hex32("1824b159acc5056f998c4fefecbc4ff55884b7fa0003480200000001fffffffd")},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var sk SecretKey
+			if err := sk.Deserialize(&c.in); err != nil {
+				t.Fatal(err)
+			}
+			if out := sk.Serialize(); out != c.out {
+				t.Fatalf("expected %x, got %x", c.out, out)
+			}
+			// the serialized key roundtrips
+			var again SecretKey
+			out := sk.Serialize()
+			if err := again.Deserialize(&out); err != nil {
+				t.Fatal(err)
+			}
+			if again != sk {
+				t.Fatal("serialized secret key does not roundtrip")
+			}
+		})
+	}
+	// the zero key serializes, but does not deserialize
+	var zero SecretKey
+	out := zero.Serialize()
+	if out != ([32]byte{}) {
+		t.Fatalf("expected zero key to serialize to zero bytes, got %x", out)
+	}
+	if err := zero.Deserialize(&out); !errors.Is(err, ErrSecretKey) {
+		t.Fatalf("expected zero key to be rejected, got: %v", err)
+	}
 }
 
 type deserializationG1TestCase struct {
@@ -186,6 +250,69 @@ func TestSkToPk(t *testing.T) {
 	}
 }
 
+type keyGenTestCase struct {
+	IKM     string
+	KeyInfo string
+	SK      string // decimal
+}
+
+// KeyGen with an empty key_info is the hkdf_mod_r function of EIP-2333,
+// the master-key test vectors of EIP-2333 apply to it.
+var keyGenTestCases = []keyGenTestCase{
+	{
+		IKM: "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		SK:  "6083874454709270928345386274498605044986640685124978867557563392430687146096",
+	},
+	{
+		IKM: "3141592653589793238462643383279502884197169399375105820974944592",
+		SK:  "29757020647961307431480504535336562678282505419141012933316116377660817309383",
+	},
+	{
+		IKM: "0099ff991111002299dd7744ee3355bbdd8844115566cc55663355668888cc00",
+		SK:  "27580842291869792442942448775674722299803720648445448686099262467207037398656",
+	},
+	{
+		IKM: "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		SK:  "19022158461524446591288038168518313374041767046816487870552872741050760015818",
+	},
+	// non-empty key_info, expected keys computed with an independent HKDF implementation
+	{
+		IKM:     "3141592653589793238462643383279502884197169399375105820974944592",
+		KeyInfo: "BLS key info",
+		SK:      "47263795894332161888723460803146865933093948185107839858852034108376394693614",
+	},
+	{
+		// a single zero byte differs from an empty key_info
+		IKM:     "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		KeyInfo: "\x00",
+		SK:      "651354893519258076106550123792820220495651452634902464753247506971218232742",
+	},
+}
+
+func TestKeyGen(t *testing.T) {
+	for i, tc := range keyGenTestCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			ikm, err := hex.DecodeString(tc.IKM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sk, err := KeyGen(ikm, []byte(tc.KeyInfo))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := (*kbls.Fr)(sk).ToBig().String()
+			if got != tc.SK {
+				t.Fatalf("expected %s, got secret key %s", tc.SK, got)
+			}
+		})
+	}
+	t.Run("short_ikm", func(t *testing.T) {
+		if _, err := KeyGen(make([]byte, 31), nil); err == nil {
+			t.Fatal("expected error for IKM shorter than 32 bytes")
+		}
+	})
+}
+
 type signTestCase struct {
 	Input struct {
 		Privkey hexStr32 `json:"privkey"`
@@ -530,3 +657,208 @@ func runTestCases(t *testing.T, path string, runCase func(t *testing.T, getData
 		})
 	})
 }
+
+func TestAugSignVerify(t *testing.T) {
+	sk := randSK(t)
+	pk, err := SkToPk(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hello aug")
+	sig := AugSign(sk, msg)
+	if !AugVerify(pk, msg, sig) {
+		t.Fatal("expected valid aug signature")
+	}
+	if AugVerify(pk, []byte("other"), sig) {
+		t.Fatal("expected invalid aug signature for other message")
+	}
+	otherPk, err := SkToPk(randSK(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if AugVerify(otherPk, msg, sig) {
+		t.Fatal("expected invalid aug signature for other pubkey")
+	}
+	// the aug scheme uses its own DST, and the pubkey prefix
+	if Verify(pk, msg, sig) {
+		t.Fatal("aug signature must not be valid in the POP scheme")
+	}
+	if AugVerify(pk, msg, Sign(sk, msg)) {
+		t.Fatal("POP signature must not be valid in the aug scheme")
+	}
+}
+
+func TestAugAggregateVerify(t *testing.T) {
+	n := 5
+	pubkeys := make([]*Pubkey, n, n)
+	messages := make([][]byte, n, n)
+	sigs := make([]*Signature, n, n)
+	for i := 0; i < n; i++ {
+		sk := randSK(t)
+		pk, err := SkToPk(sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubkeys[i] = pk
+		// the aug scheme allows for duplicate messages
+		messages[i] = []byte("same message")
+		sigs[i] = AugSign(sk, messages[i])
+	}
+	agg, err := Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !AugAggregateVerify(pubkeys, messages, agg) {
+		t.Fatal("expected valid aug aggregate signature")
+	}
+	if AugAggregateVerify(pubkeys[1:], messages[1:], agg) {
+		t.Fatal("expected invalid aug aggregate signature for subset")
+	}
+	if AugAggregateVerify(pubkeys, messages[1:], agg) {
+		t.Fatal("expected length mismatch to be invalid")
+	}
+	if AugAggregateVerify(nil, nil, agg) {
+		t.Fatal("expected empty input to be invalid")
+	}
+	var identity Pubkey
+	(*kbls.PointG1)(&identity).Zero()
+	withIdentity := append([]*Pubkey{&identity}, pubkeys...)
+	withIdentityMsgs := append([][]byte{[]byte("x")}, messages...)
+	if AugAggregateVerify(withIdentity, withIdentityMsgs, agg) {
+		t.Fatal("expected identity pubkey to be invalid")
+	}
+}
+
+func TestBasicSignVerify(t *testing.T) {
+	sk := randSK(t)
+	pk, err := SkToPk(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("hello basic")
+	sig := BasicSign(sk, msg)
+	if !BasicVerify(pk, msg, sig) {
+		t.Fatal("expected valid basic signature")
+	}
+	if BasicVerify(pk, []byte("other"), sig) {
+		t.Fatal("expected invalid basic signature for other message")
+	}
+	// the basic scheme uses its own DST
+	if Verify(pk, msg, sig) {
+		t.Fatal("basic signature must not be valid in the POP scheme")
+	}
+	if BasicVerify(pk, msg, Sign(sk, msg)) {
+		t.Fatal("POP signature must not be valid in the basic scheme")
+	}
+}
+
+func TestBasicAggregateVerify(t *testing.T) {
+	n := 5
+	pubkeys := make([]*Pubkey, n, n)
+	messages := make([][]byte, n, n)
+	sigs := make([]*Signature, n, n)
+	for i := 0; i < n; i++ {
+		sk := randSK(t)
+		pk, err := SkToPk(sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubkeys[i] = pk
+		messages[i] = []byte(fmt.Sprintf("message %d", i))
+		sigs[i] = BasicSign(sk, messages[i])
+	}
+	agg, err := Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !BasicAggregateVerify(pubkeys, messages, agg) {
+		t.Fatal("expected valid basic aggregate signature")
+	}
+	if BasicAggregateVerify(nil, nil, agg) {
+		t.Fatal("expected empty input to be invalid")
+	}
+
+	// duplicate messages are rejected, even if the aggregate signature is valid
+	sk := randSK(t)
+	pk, err := SkToPk(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dupSig, err := Aggregate([]*Signature{agg, BasicSign(sk, messages[2])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dupPubkeys := append(append([]*Pubkey{}, pubkeys...), pk)
+	dupMessages := append(append([][]byte{}, messages...), messages[2])
+	if err := coreAggregateVerify(dupPubkeys, dupMessages, dupSig, basicDomain); err != nil {
+		t.Fatalf("expected valid core aggregate signature: %v", err)
+	}
+	if BasicAggregateVerify(dupPubkeys, dupMessages, dupSig) {
+		t.Fatal("expected duplicate messages to be invalid")
+	}
+}
+
+func TestPopProveVerify(t *testing.T) {
+	sk := randSK(t)
+	pk, err := SkToPk(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := PopProve(sk)
+	if !PopVerify(pk, proof) {
+		t.Fatal("expected valid proof of possession")
+	}
+	otherPk, err := SkToPk(randSK(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PopVerify(otherPk, proof) {
+		t.Fatal("expected proof to be invalid for other pubkey")
+	}
+	// the proof uses its own DST, a signature over the pubkey is not a proof
+	pkRaw := pk.Serialize()
+	if PopVerify(pk, Sign(sk, pkRaw[:])) {
+		t.Fatal("expected signature over the pubkey to be an invalid proof")
+	}
+	if Verify(pk, pkRaw[:], proof) {
+		t.Fatal("expected proof to be an invalid signature")
+	}
+	var identity Pubkey
+	(*kbls.PointG1)(&identity).Zero()
+	var identitySig Signature
+	(*kbls.PointG2)(&identitySig).Zero()
+	if PopVerify(&identity, &identitySig) {
+		t.Fatal("expected identity pubkey to be invalid")
+	}
+}
+
+// TestSignZeroKey checks that all signing functions treat a zero secret key the same:
+// the result is the identity signature, which does not verify.
+func TestSignZeroKey(t *testing.T) {
+	var zero SecretKey
+	var identity Pubkey
+	(*kbls.PointG1)(&identity).Zero()
+	msg := []byte("zero key")
+	proof := PopProve(&zero)
+	sigs := map[string]*Signature{
+		"Sign":      Sign(&zero, msg),
+		"AugSign":   AugSign(&zero, msg),
+		"BasicSign": BasicSign(&zero, msg),
+		"PopProve":  proof,
+	}
+	for name, sig := range sigs {
+		if !kbls.NewG2().IsZero((*kbls.PointG2)(sig)) {
+			t.Fatalf("expected %s with a zero key to result in the identity signature", name)
+		}
+	}
+	if Verify(&identity, msg, sigs["Sign"]) || AugVerify(&identity, msg, sigs["AugSign"]) ||
+		BasicVerify(&identity, msg, sigs["BasicSign"]) || PopVerify(&identity, proof) {
+		t.Fatal("expected the identity signature to not verify")
+	}
+	proofG1 := PopProveG1(&zero)
+	for name, sig := range map[string]*SignatureG1{"SignG1": SignG1(&zero, msg), "PopProveG1": proofG1} {
+		if !kbls.NewG1().IsZero((*kbls.PointG1)(sig)) {
+			t.Fatalf("expected %s with a zero key to result in the identity signature", name)
+		}
+	}
+}
@@ -1,119 +1,469 @@
 package blsu
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	kbls "github.com/kilic/bls12-381"
+	"runtime"
+	"sort"
+	"sync"
 )
 
-type rhsWork struct {
-	pub *kbls.PointG1
-	msg *kbls.PointG2
+// DefaultSignatureSetMinChunkSize is the default minimum number of tuples per worker of a signature set verification.
+const DefaultSignatureSetMinChunkSize = 4
+
+// SignatureSetOptions configures the parallelism of signature set verification. The zero value is valid.
+type SignatureSetOptions struct {
+	// Workers is the maximum number of goroutines that hash and multiply the tuples, and compute the pairings, in parallel.
+	// Defaults to GOMAXPROCS.
+	Workers int
+	// MinChunkSize is the minimum number of tuples per worker: small sets use fewer workers.
+	// Defaults to DefaultSignatureSetMinChunkSize.
+	MinChunkSize int
+	// SingleThreaded runs all work in the calling goroutine, without starting any goroutines.
+	// This overrides Workers.
+	SingleThreaded bool
 }
 
-type lhsWork struct {
-	sig *kbls.PointG2
+// workerCount returns the number of workers to process n > 0 items with, e.g. tuples or messages. The options may be nil.
+func (opts *SignatureSetOptions) workerCount(n uint) uint {
+	if opts == nil {
+		opts = &SignatureSetOptions{}
+	}
+	if opts.SingleThreaded {
+		return 1
+	}
+	workers := uint(runtime.GOMAXPROCS(0))
+	if opts.Workers > 0 {
+		workers = uint(opts.Workers)
+	}
+	minChunkSize := uint(DefaultSignatureSetMinChunkSize)
+	if opts.MinChunkSize > 0 {
+		minChunkSize = uint(opts.MinChunkSize)
+	}
+	if maxWorkers := n / minChunkSize; workers > maxWorkers {
+		workers = maxWorkers
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// runChunks splits n items into workerCount contiguous chunks, and runs fn on each chunk, in parallel.
+// A single chunk runs in the calling goroutine. It returns when all chunks are done.
+func runChunks(n uint, workerCount uint, fn func(start uint, end uint)) {
+	if workerCount <= 1 {
+		fn(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(int(workerCount))
+	for i := uint(0); i < workerCount; i++ {
+		start := n * i / workerCount
+		end := (n * (i + 1)) / workerCount
+		go func() {
+			defer wg.Done()
+			fn(start, end)
+		}()
+	}
+	wg.Wait()
 }
 
 // SignatureSetVerify verifies (pubkey,message,signature) tuples as a single batch,
 // and is faster than len(inputs) times Verify if the batch is not too small.
 //
-// The caller should aggregate pubkeys and signatures if they signed the same message,
-// this verification is safe to run on duplicate inputs, but not optimized for it.
+// Tuples with the same message, e.g. attestations of the same vote, are grouped:
+// each distinct message is hashed once, and its pubkeys are merged with random weights,
+// so the number of pairings is the number of distinct messages, not the number of tuples.
 //
 // A completely empty input is also considered to be VALID.
 //
-// This function parallelizes the scalar-multiplication and signature-aggregation work.
+// This function parallelizes the hashing, scalar-multiplication and pairing work,
+// with the default SignatureSetOptions, see SignatureSetVerifyWithOptions.
+// The pairings are split over the workers, see checkPairings.
 //
 // An error is returned if the verification failed due to an operational error,
 // e.g. input length mismatch or failing to read entropy bytes with the crypto/rand package.
+// A tuple with the identity pubkey or signature is INVALID, and rejected before batching, without an error:
+// see SignatureSetVerifyDetailed to find such tuples.
 //
 // Original: https://ethresear.ch/t/fast-verification-of-multiple-bls-signatures/5407
 func SignatureSetVerify(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature) (bool, error) {
+	return PopCiphersuite.SignatureSetVerifyWithOptions(pubkeys, messages, signatures, nil)
+}
+
+// SignatureSetVerifyWithOptions is SignatureSetVerify, with options to configure the parallelism.
+// The options may be nil to use the defaults.
+func SignatureSetVerifyWithOptions(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) (bool, error) {
+	return PopCiphersuite.SignatureSetVerifyContext(context.Background(), pubkeys, messages, signatures, opts)
+}
+
+// SignatureSetVerifyContext is SignatureSetVerifyWithOptions, but stops the hashing and pairing work
+// when the context is cancelled, and then returns the context error. The options may be nil to use the defaults.
+//
+// Cancellation is checked between the tuples, and between pairing products of at most cancellablePairs pairs.
+// All workers have stopped when this function returns.
+// All workers have stopped when this function returns.
+func SignatureSetVerifyContext(ctx context.Context, pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) (bool, error) {
+	return PopCiphersuite.SignatureSetVerifyContext(ctx, pubkeys, messages, signatures, opts)
+}
+
+// SignatureSetVerify is SignatureSetVerify in the ciphersuite, see the package-level SignatureSetVerify.
+// Each tuple is verified as with Verify, i.e. messages are augmented with the pubkey in the Aug scheme.
+func (cs *Ciphersuite) SignatureSetVerify(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature) (bool, error) {
+	return cs.SignatureSetVerifyWithOptions(pubkeys, messages, signatures, nil)
+}
+
+// SignatureSetVerifyWithOptions is SignatureSetVerify in the ciphersuite, with options to configure the parallelism.
+// The options may be nil to use the defaults.
+func (cs *Ciphersuite) SignatureSetVerifyWithOptions(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) (bool, error) {
+	return cs.SignatureSetVerifyContext(context.Background(), pubkeys, messages, signatures, opts)
+}
+
+// SignatureSetVerifyContext is SignatureSetVerify in the ciphersuite, cancellable with the context,
+// see the package-level SignatureSetVerifyContext.
+func (cs *Ciphersuite) SignatureSetVerifyContext(ctx context.Context, pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) (bool, error) {
 	n := uint(len(pubkeys))
 	if uint(len(messages)) != n || uint(len(signatures)) != n {
-		return false, fmt.Errorf("input length mismatch: pubs: %d, msgs: %d, sigs: %d", n, len(messages), len(signatures))
+		return false, fmt.Errorf("%w: pubs: %d, msgs: %d, sigs: %d", ErrLengthMismatch, n, len(messages), len(signatures))
 	}
 	if n == 0 {
 		return true, nil
 	}
+	for i := range pubkeys {
+		if checkSigSetIdentity(pubkeys[i], signatures[i]) != nil {
+			return false, nil
+		}
+	}
 	// Random 64 bits scalars are considered safe, as there is no repeated verification with the same randomness.
 	// Fetch all randomness at once, to not cause blocking problems, and not deal with concurrent error handling.
 	rngBuf := make([]byte, n*64, n*64)
 	// the first entry does not need randomness
 	if _, err := rand.Read(rngBuf[64:]); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrEntropy, err)
+	}
+	items, err := cs.prepareSigSetItems(ctx, pubkeys, messages, signatures, rngBuf, true, opts)
+	if err != nil {
 		return false, err
 	}
-	// return aggregated pubkey, rand-agg. signature, rand-agg. message, error.
-	worker := func(offset uint, pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, lhsCh chan<- lhsWork, rhsCh chan<- rhsWork) {
-		// scratchpad
-		g2 := kbls.NewG2()
-		sigCopy := *(*kbls.PointG2)(signatures[0])
-		aggSig := &sigCopy
-		// error only occurs on invalid domain length
-		msg, _ := g2.HashToCurve(messages[0], domain)
-		// Optimization: We do not multiply the first signature and message entry with a random scalar,
-		// the security depends on not being able to manipulate the delta between the inputs.
-		// This only applies to the first worker
-		if offset != 0 {
-			var randScalar kbls.Fr
-			randScalar.FromBytes(rngBuf[offset : offset+64]) // TODO: 64 bytes avoids modulo bias, but maybe we should just use the random Fr func (more calls to RNG, but less data)
-			offset += 64
-			g2.MulScalar(aggSig, aggSig, &randScalar)
-			g2.MulScalar(msg, msg, &randScalar)
-		}
-		rhsCh <- rhsWork{(*kbls.PointG1)(pubkeys[0]), msg}
+	return checkSigSetItems(ctx, items, opts)
+}
 
-		var tmpSig kbls.PointG2
-		var randScalar kbls.Fr
-		for i := 1; i < len(pubkeys); i++ {
-			randScalar.FromBytes(rngBuf[offset : offset+64])
-			offset += 64
+// SignatureSetVerifyDetailed is SignatureSetVerify, but returns the indices of the INVALID tuples, in ascending order.
+// If the batch is VALID, no indices are returned.
+// Tuples with the identity pubkey or signature are INVALID, and left out of the batch.
+//
+// The tuples are verified with the batch equation of SignatureSetVerify. If the batch fails,
+// it is searched with recursive bisection: each half is checked as its own batch,
+// reusing the hashed messages and random scalars, so only the pairings are recomputed.
+// If a half is valid, the other half is known to be invalid without checking it.
+// With k invalid tuples out of n, this takes in the order of 2*k*log2(n) batch checks.
+func SignatureSetVerifyDetailed(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature) ([]int, error) {
+	return PopCiphersuite.SignatureSetVerifyDetailedWithOptions(pubkeys, messages, signatures, nil)
+}
 
-			tmpSig = *(*kbls.PointG2)(signatures[i])
-			g2.MulScalar(&tmpSig, &tmpSig, &randScalar)
-			g2.Add(aggSig, aggSig, &tmpSig)
+// SignatureSetVerifyDetailedWithOptions is SignatureSetVerifyDetailed, with options to configure the parallelism.
+// The options may be nil to use the defaults.
+func SignatureSetVerifyDetailedWithOptions(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) ([]int, error) {
+	return PopCiphersuite.SignatureSetVerifyDetailedContext(context.Background(), pubkeys, messages, signatures, opts)
+}
 
-			// error only occurs on invalid domain length
-			msg, _ := g2.HashToCurve(messages[i], domain)
-			g2.MulScalar(msg, msg, &randScalar)
-			pub := (*kbls.PointG1)(pubkeys[i])
-			rhsCh <- rhsWork{pub, msg}
+// SignatureSetVerifyDetailedContext is SignatureSetVerifyDetailedWithOptions, but stops the work
+// when the context is cancelled, like SignatureSetVerifyContext, and then returns the context error.
+func SignatureSetVerifyDetailedContext(ctx context.Context, pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) ([]int, error) {
+	return PopCiphersuite.SignatureSetVerifyDetailedContext(ctx, pubkeys, messages, signatures, opts)
+}
+
+// sigSetItem is a tuple of a signature set, prepared for batch checks:
+// the pubkey and signature are multiplied by the same random scalar.
+// Tuples with the same message share the hashed message, and have the same group.
+type sigSetItem struct {
+	pub   *kbls.PointG1
+	msg   *kbls.PointG2
+	group int
+	sig   *kbls.PointG2
+}
+
+// SignatureSetVerifyDetailed is SignatureSetVerifyDetailed in the ciphersuite, see the package-level SignatureSetVerifyDetailed.
+func (cs *Ciphersuite) SignatureSetVerifyDetailed(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature) ([]int, error) {
+	return cs.SignatureSetVerifyDetailedWithOptions(pubkeys, messages, signatures, nil)
+}
+
+// SignatureSetVerifyDetailedWithOptions is SignatureSetVerifyDetailed in the ciphersuite, with options to configure the parallelism.
+// The options may be nil to use the defaults.
+func (cs *Ciphersuite) SignatureSetVerifyDetailedWithOptions(pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) ([]int, error) {
+	return cs.SignatureSetVerifyDetailedContext(context.Background(), pubkeys, messages, signatures, opts)
+}
+
+// SignatureSetVerifyDetailedContext is SignatureSetVerifyDetailed in the ciphersuite, cancellable with the context,
+// see the package-level SignatureSetVerifyDetailedContext.
+func (cs *Ciphersuite) SignatureSetVerifyDetailedContext(ctx context.Context, pubkeys []*Pubkey, messages [][]byte, signatures []*Signature, opts *SignatureSetOptions) ([]int, error) {
+	n := uint(len(pubkeys))
+	if uint(len(messages)) != n || uint(len(signatures)) != n {
+		return nil, fmt.Errorf("%w: pubs: %d, msgs: %d, sigs: %d", ErrLengthMismatch, n, len(messages), len(signatures))
+	}
+	// indices maps the batched tuples to the inputs
+	var invalid []int
+	indices := make([]int, 0, n)
+	for i := range pubkeys {
+		if checkSigSetIdentity(pubkeys[i], signatures[i]) != nil {
+			invalid = append(invalid, i)
+		} else {
+			indices = append(indices, i)
 		}
-		lhsCh <- lhsWork{&sigCopy}
 	}
+	if len(invalid) > 0 {
+		batchPubkeys := make([]*Pubkey, len(indices), len(indices))
+		batchMessages := make([][]byte, len(indices), len(indices))
+		batchSignatures := make([]*Signature, len(indices), len(indices))
+		for j, i := range indices {
+			batchPubkeys[j], batchMessages[j], batchSignatures[j] = pubkeys[i], messages[i], signatures[i]
+		}
+		pubkeys, messages, signatures = batchPubkeys, batchMessages, batchSignatures
+		n = uint(len(indices))
+	}
+	if n == 0 {
+		return invalid, nil
+	}
+	// Unlike SignatureSetVerify, every tuple gets a random scalar: sub-batches are checked separately,
+	// and cannot all include the first tuple.
+	rngBuf := make([]byte, n*64, n*64)
+	if _, err := rand.Read(rngBuf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEntropy, err)
+	}
+	items, err := cs.prepareSigSetItems(ctx, pubkeys, messages, signatures, rngBuf, false, opts)
+	if err != nil {
+		return nil, err
+	}
+	if valid, err := checkSigSetItems(ctx, items, opts); err != nil {
+		return nil, err
+	} else if valid {
+		return invalid, nil
+	}
+	found, err := bisectSigSetItems(ctx, items, 0, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range found {
+		invalid = append(invalid, indices[j])
+	}
+	sort.Ints(invalid)
+	return invalid, nil
+}
 
-	// TODO: adjust worker count dynamically based on environment
-	workerCount := uint(4)
-	if n < workerCount {
-		workerCount = n
+// checkSigSetIdentity returns ErrIdentityPubkey, or an ErrIdentitySignature like Verify, if the tuple has the identity pubkey or signature.
+// Such a tuple is INVALID, like in Verify, but may not fail the batch equation:
+// the identity signature of the identity pubkey pairs to one, and cancels out of the batch.
+func checkSigSetIdentity(pub *Pubkey, sig *Signature) error {
+	if (*kbls.G1)(nil).IsZero((*kbls.PointG1)(pub)) {
+		return ErrIdentityPubkey
+	}
+	if (*kbls.G2)(nil).IsZero((*kbls.PointG2)(sig)) {
+		return errIdentitySignature
 	}
+	return nil
+}
 
-	// Note: the channel buffers are big enough to collect all work,
-	// and thus empty one channel before the other, avoiding deadlocks
-	lhsCh := make(chan lhsWork, workerCount)
-	rhsCh := make(chan rhsWork, n)
+// prepareSigSetItems hashes each distinct message once, and multiplies the pubkeys and signatures
+// with the random scalars, read from 64 random bytes per tuple.
+// If skipFirst, the first tuple is not multiplied, and its randomness is not used.
+// The workers stop when the context is cancelled, and the context error is returned.
+//
+// The scalars are applied to the pubkeys instead of the hashed messages:
+// e(r*P, H(m)) == e(P, r*H(m)), and multiplication in G1 is cheaper than in G2.
+// This also allows tuples with the same message to share a single pairing, see checkSigSetItems.
+func (cs *Ciphersuite) prepareSigSetItems(ctx context.Context, pubkeys []*Pubkey, messages [][]byte, signatures []*Signature,
+	rngBuf []byte, skipFirst bool, opts *SignatureSetOptions) ([]sigSetItem, error) {
+	n := uint(len(pubkeys))
+	// group the tuples by message. In the Aug scheme, the pubkey is part of the message.
+	groups := make([]int, n, n)
+	var distinct [][]byte
+	index := make(map[string]int)
+	for i := range pubkeys {
+		msg := cs.message(pubkeys[i], messages[i])
+		j, ok := index[string(msg)]
+		if !ok {
+			j = len(distinct)
+			index[string(msg)] = j
+			distinct = append(distinct, msg)
+		}
+		groups[i] = j
+	}
+	hashed := make([]*kbls.PointG2, len(distinct), len(distinct))
+	runChunks(uint(len(distinct)), opts.workerCount(uint(len(distinct))), func(start uint, end uint) {
+		// scratchpad
+		g2 := kbls.NewG2()
+		for j := start; j < end; j++ {
+			if ctx.Err() != nil {
+				return
+			}
+			// error only occurs on invalid domain length
+			hashed[j], _ = g2.HashToCurve(distinct[j], cs.dst)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	for i := uint(0); i < workerCount; i++ {
-		start := n * i / workerCount
-		end := (n * (i + 1)) / workerCount
-		go worker(start*64, pubkeys[start:end], messages[start:end], signatures[start:end], lhsCh, rhsCh)
+	items := make([]sigSetItem, n, n)
+	runChunks(n, opts.workerCount(n), func(start uint, end uint) {
+		// scratchpad
+		g1 := kbls.NewG1()
+		g2 := kbls.NewG2()
+		var randScalar kbls.Fr
+		for i := start; i < end; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+			pub := *(*kbls.PointG1)(pubkeys[i])
+			sig := *(*kbls.PointG2)(signatures[i])
+			// Optimization: We do not multiply the first pubkey and signature entry with a random scalar,
+			// the security depends on not being able to manipulate the delta between the inputs.
+			if i != 0 || !skipFirst {
+				randScalar.FromBytes(rngBuf[i*64 : (i+1)*64]) // TODO: 64 bytes avoids modulo bias, but maybe we should just use the random Fr func (more calls to RNG, but less data)
+				g1.MulScalar(&pub, &pub, &randScalar)
+				g2.MulScalar(&sig, &sig, &randScalar)
+			}
+			items[i] = sigSetItem{pub: &pub, msg: hashed[groups[i]], group: groups[i], sig: &sig}
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	return items, nil
+}
+
+// sigSetPair is a pairing input of a batch check: the sum of the weighted pubkeys that signed the hashed message.
+type sigSetPair struct {
+	pub kbls.PointG1
+	msg *kbls.PointG2
+}
 
-	// scratchpad
+// checkSigSetItems checks the items as a single batch.
+//
+// Items with the same message share a pairing: e(P1, H(m)) * e(P2, H(m)) == e(P1 + P2, H(m)),
+// so the number of pairings is the number of distinct messages, plus one for the aggregate signature.
+func checkSigSetItems(ctx context.Context, items []sigSetItem, opts *SignatureSetOptions) (bool, error) {
+	g1 := kbls.NewG1()
 	g2 := kbls.NewG2()
-	eng := kbls.NewEngine()
+	aggSig := *items[0].sig
+	for i := 1; i < len(items); i++ {
+		g2.Add(&aggSig, &aggSig, items[i].sig)
+	}
+	var pairs []sigSetPair
+	index := make(map[int]int)
+	for _, item := range items {
+		if j, ok := index[item.group]; ok {
+			g1.Add(&pairs[j].pub, &pairs[j].pub, item.pub)
+			continue
+		}
+		index[item.group] = len(pairs)
+		pairs = append(pairs, sigSetPair{pub: *item.pub, msg: item.msg})
+	}
+	return checkPairings(ctx, pairs, &aggSig, opts)
+}
+
+// cancellablePairs is the maximum number of pairs in a single pairing product, if the pairing work can be cancelled:
+// a pairing product cannot be interrupted, and each product takes its own final exponentiation,
+// which costs about as much as a few pairs, so this trades a little throughput for a prompt cancellation.
+const cancellablePairs = 16
 
-	aggSig := (<-lhsCh).sig
-	for i := uint(1); i < workerCount; i++ {
-		g2.Add(aggSig, aggSig, (<-lhsCh).sig)
+// checkPairings checks if the product of the pairings of the pairs, and the pairing of
+// the negated generator and aggregate signature, is one. I.e. if the aggregate signature matches the pairs.
+//
+// The pairs are split over the workers of the options: each worker computes the pairing product of its chunk,
+// and the products are multiplied together. kilic/bls12-381 does not expose the Miller loop by itself,
+// so each product includes its own final exponentiation: e(P1, Q1) * e(P2, Q2) is the same in GT,
+// whether computed with one final exponentiation, or one per chunk. The final exponentiations run in parallel,
+// so the wall-clock cost is about that of a single one.
+//
+// If the context can be cancelled, each worker splits its chunk into products of at most cancellablePairs pairs,
+// and checks the context before each product. All workers have stopped when this function returns.
+func checkPairings(ctx context.Context, pairs []sigSetPair, aggSig *kbls.PointG2, opts *SignatureSetOptions) (bool, error) {
+	cancellable := ctx.Done() != nil
+	// the first pair is that of the negated generator and the aggregate signature
+	n := uint(len(pairs)) + 1
+	gt := kbls.NewGT()
+	product := new(kbls.E).One()
+	var mu sync.Mutex
+	var cancelErr error
+	runChunks(n, opts.workerCount(n), func(start uint, end uint) {
+		// scratchpad
+		localGT := kbls.NewGT()
+		local := new(kbls.E).One()
+		eng := kbls.NewEngine()
+		count := 0
+		// multiplies the product of the pairs in the engine into the local product, unless cancelled
+		flush := func() bool {
+			if cancellable {
+				if err := ctx.Err(); err != nil {
+					mu.Lock()
+					cancelErr = err
+					mu.Unlock()
+					return false
+				}
+			}
+			// Result resets the engine
+			localGT.Mul(local, local, eng.Result())
+			count = 0
+			return true
+		}
+		for i := start; i < end; i++ {
+			if cancellable && count == cancellablePairs {
+				if !flush() {
+					return
+				}
+			}
+			if i == 0 {
+				eng.AddPairInv(&kbls.G1One, aggSig)
+			} else {
+				eng.AddPair(&pairs[i-1].pub, pairs[i-1].msg)
+			}
+			count++
+		}
+		if !flush() {
+			return
+		}
+		mu.Lock()
+		gt.Mul(product, product, local)
+		mu.Unlock()
+	})
+	if cancelErr != nil {
+		return false, cancelErr
+	}
+	return product.IsOne(), nil
+}
+
+// bisectSigSetItems appends the indices of the invalid items to out, offset by the given index.
+// The items must be known to be invalid as a batch.
+func bisectSigSetItems(ctx context.Context, items []sigSetItem, offset int, out []int, opts *SignatureSetOptions) ([]int, error) {
+	if len(items) == 1 {
+		return append(out, offset), nil
 	}
-	for i := uint(0); i < n; i++ {
-		pair := <-rhsCh
-		eng.AddPair(pair.pub, pair.msg)
+	mid := len(items) / 2
+	left, right := items[:mid], items[mid:]
+	leftValid, err := checkSigSetItems(ctx, left, opts)
+	if err != nil {
+		return nil, err
 	}
-	eng.AddPairInv(&kbls.G1One, aggSig)
-	return eng.Check(), nil
+	if leftValid {
+		// the left half is valid, so the right half must be invalid
+		return bisectSigSetItems(ctx, right, offset+mid, out, opts)
+	}
+	out, err = bisectSigSetItems(ctx, left, offset, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	rightValid, err := checkSigSetItems(ctx, right, opts)
+	if err != nil {
+		return nil, err
+	}
+	if rightValid {
+		return out, nil
+	}
+	return bisectSigSetItems(ctx, right, offset+mid, out, opts)
 }
 
 type SignatureSet struct {
@@ -132,3 +482,8 @@ func (s *SignatureSet) Verify() bool {
 	valid, _ := SignatureSetVerify(s.pubkeys, s.messages, s.signatures)
 	return valid
 }
+
+// VerifyDetailed returns the indices of the invalid signatures in the set, see SignatureSetVerifyDetailed.
+func (s *SignatureSet) VerifyDetailed() ([]int, error) {
+	return SignatureSetVerifyDetailed(s.pubkeys, s.messages, s.signatures)
+}
@@ -1,6 +1,8 @@
 package blsu
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"testing"
 )
@@ -23,3 +25,63 @@ func BenchmarkSignatureSetVerify(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkSignatureSetVerifyWorkers(b *testing.B) {
+	pubs, msgs, sigs := prepareSignatureSetTest(b, 128)
+	for _, workers := range []int{1, 4, 16} {
+		opts := &SignatureSetOptions{Workers: workers}
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if valid, err := SignatureSetVerifyWithOptions(pubs, msgs, sigs, opts); err != nil || !valid {
+					b.Fatal("expected valid set")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSignatureSetVerifyGrouped(b *testing.B) {
+	// 128 attestations of 4 distinct votes
+	n := 128
+	pubs := make([]*Pubkey, n, n)
+	msgs := make([][]byte, n, n)
+	sigs := make([]*Signature, n, n)
+	for i := 0; i < n; i++ {
+		sk := randSK(b)
+		pub, err := SkToPk(sk)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pubs[i] = pub
+		msgs[i] = []byte(fmt.Sprintf("vote %d", i%4))
+		sigs[i] = Sign(sk, msgs[i])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if valid, err := SignatureSetVerify(pubs, msgs, sigs); err != nil || !valid {
+			b.Fatal("expected valid set")
+		}
+	}
+}
+
+func BenchmarkCheckPairings(b *testing.B) {
+	pubs, msgs, sigs := prepareSignatureSetTest(b, 128)
+	rngBuf := make([]byte, 128*64, 128*64)
+	if _, err := rand.Read(rngBuf); err != nil {
+		b.Fatal(err)
+	}
+	items, err := PopCiphersuite.prepareSigSetItems(context.Background(), pubs, msgs, sigs, rngBuf, true, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, workers := range []int{1, 4, 16} {
+		opts := &SignatureSetOptions{Workers: workers}
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if valid, err := checkSigSetItems(context.Background(), items, opts); err != nil || !valid {
+					b.Fatal("expected valid set")
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package blsu
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	kbls "github.com/kilic/bls12-381"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
 )
 
 func randSK(t testing.TB) *SecretKey {
@@ -52,6 +57,326 @@ func TestSignatureSetVerifyRandom(t *testing.T) {
 	}
 }
 
+func TestSignatureSetOptions(t *testing.T) {
+	maxProcs := uint(runtime.GOMAXPROCS(0))
+	cases := []struct {
+		name     string
+		opts     *SignatureSetOptions
+		n        uint
+		expected uint
+	}{
+		{"nil small", nil, 3, 1},
+		{"nil large", nil, DefaultSignatureSetMinChunkSize * maxProcs * 2, maxProcs},
+		{"single threaded", &SignatureSetOptions{Workers: 32, SingleThreaded: true}, 1000, 1},
+		{"workers", &SignatureSetOptions{Workers: 32}, 1000, 32},
+		{"workers capped by chunk size", &SignatureSetOptions{Workers: 32}, 40, 10},
+		{"min chunk size", &SignatureSetOptions{Workers: 32, MinChunkSize: 100}, 1000, 10},
+		{"chunk size 1", &SignatureSetOptions{Workers: 32, MinChunkSize: 1}, 5, 5},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.opts.workerCount(c.n); got != c.expected {
+				t.Fatalf("expected %d workers, got %d", c.expected, got)
+			}
+		})
+	}
+
+	pubs, msgs, sigs := prepareSignatureSetTest(t, 13)
+	for _, opts := range []*SignatureSetOptions{
+		nil,
+		{SingleThreaded: true},
+		{Workers: 3, MinChunkSize: 1},
+		{Workers: 32, MinChunkSize: 1},
+	} {
+		t.Run(fmt.Sprintf("verify_%+v", opts), func(t *testing.T) {
+			valid, err := SignatureSetVerifyWithOptions(pubs, msgs, sigs, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Fatal("expected set to be valid")
+			}
+			invalidSigs := append([]*Signature(nil), sigs...)
+			invalidSigs[4], invalidSigs[11] = sigs[11], sigs[4]
+			valid, err = SignatureSetVerifyWithOptions(pubs, msgs, invalidSigs, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid {
+				t.Fatal("expected set to be invalid")
+			}
+			invalid, err := SignatureSetVerifyDetailedWithOptions(pubs, msgs, invalidSigs, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(invalid) != "[4 11]" {
+				t.Fatalf("expected invalid indices [4 11], got %v", invalid)
+			}
+		})
+	}
+}
+
+func TestSignatureSetGrouping(t *testing.T) {
+	// 3 votes, signed by 4, 1 and 3 validators
+	votes := [][]byte{[]byte("vote a"), []byte("vote b"), []byte("vote c")}
+	voteOf := []int{0, 0, 1, 0, 2, 2, 0, 2}
+	var pubs []*Pubkey
+	var msgs [][]byte
+	var sigs []*Signature
+	for _, v := range voteOf {
+		sk := randSK(t)
+		pub, err := SkToPk(sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, pub)
+		msgs = append(msgs, votes[v])
+		sigs = append(sigs, Sign(sk, votes[v]))
+	}
+
+	rngBuf := make([]byte, len(pubs)*64)
+	if _, err := rand.Read(rngBuf); err != nil {
+		t.Fatal(err)
+	}
+	items, err := PopCiphersuite.prepareSigSetItems(context.Background(), pubs, msgs, sigs, rngBuf, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := make(map[int]*kbls.PointG2)
+	for i, item := range items {
+		if item.group != voteOf[i] {
+			t.Fatalf("tuple %d: expected group %d, got %d", i, voteOf[i], item.group)
+		}
+		if msg, ok := hashed[item.group]; ok && msg != item.msg {
+			t.Fatalf("tuple %d: expected the hashed message to be shared", i)
+		}
+		hashed[item.group] = item.msg
+	}
+	// in the Aug scheme the pubkey is part of the message, nothing is grouped
+	augItems, err := AugCiphersuite.prepareSigSetItems(context.Background(), pubs, msgs, sigs, rngBuf, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, item := range augItems {
+		if item.group != i {
+			t.Fatalf("tuple %d: expected Aug group %d, got %d", i, i, item.group)
+		}
+	}
+
+	for _, opts := range []*SignatureSetOptions{nil, {SingleThreaded: true}, {Workers: 3, MinChunkSize: 1}} {
+		valid, err := SignatureSetVerifyWithOptions(pubs, msgs, sigs, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Fatalf("expected grouped set to be valid, options: %+v", opts)
+		}
+	}
+
+	// swap signatures of two validators of the same vote
+	invalidSigs := append([]*Signature(nil), sigs...)
+	invalidSigs[1], invalidSigs[3] = sigs[3], sigs[1]
+	valid, err := SignatureSetVerify(pubs, msgs, invalidSigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Fatal("expected grouped set with swapped signatures to be invalid")
+	}
+	invalid, err := SignatureSetVerifyDetailed(pubs, msgs, invalidSigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(invalid) != "[1 3]" {
+		t.Fatalf("expected invalid indices [1 3], got %v", invalid)
+	}
+}
+
+// countingContext is a context that is cancelled at the given call of Err, to cancel at a known point of the work,
+// or never if cancelAt is zero. It counts the calls of Err, i.e. the cancellation checks.
+type countingContext struct {
+	context.Context
+	mu       sync.Mutex
+	calls    int
+	cancelAt int
+	done     chan struct{}
+}
+
+func newCountingContext(cancelAt int) *countingContext {
+	return &countingContext{Context: context.Background(), cancelAt: cancelAt, done: make(chan struct{})}
+}
+
+func (c *countingContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *countingContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.cancelAt == 0 || c.calls < c.cancelAt {
+		return nil
+	}
+	if c.calls == c.cancelAt {
+		close(c.done)
+	}
+	return context.Canceled
+}
+
+// count returns the number of calls of Err.
+func (c *countingContext) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func TestSignatureSetVerifyContext(t *testing.T) {
+	// more pairs than a single cancellable pairing product
+	n := cancellablePairs*2 + 3
+	pubs, msgs, sigs := prepareSignatureSetTest(t, n)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, opts := range []*SignatureSetOptions{{SingleThreaded: true}, {Workers: 2}} {
+		valid, err := SignatureSetVerifyContext(ctx, pubs, msgs, sigs, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Fatalf("expected set to be valid, options: %+v", opts)
+		}
+		invalidSigs := append([]*Signature(nil), sigs...)
+		invalidSigs[n-1] = sigs[0]
+		valid, err = SignatureSetVerifyContext(ctx, pubs, msgs, invalidSigs, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid {
+			t.Fatalf("expected set to be invalid, options: %+v", opts)
+		}
+		invalid, err := SignatureSetVerifyDetailedContext(ctx, pubs, msgs, invalidSigs, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(invalid) != fmt.Sprint([]int{n - 1}) {
+			t.Fatalf("expected invalid index %d, got %v", n-1, invalid)
+		}
+	}
+
+	goroutines := runtime.NumGoroutine()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SignatureSetVerifyContext(cancelled, pubs, msgs, sigs, &SignatureSetOptions{Workers: 4}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+	if _, err := SignatureSetVerifyDetailedContext(cancelled, pubs, msgs, sigs, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+
+	// cancel after the hashing, during the pairings: the last cancellation checks are those of the pairing products,
+	// between the products of cancellablePairs pairs, including the aggregate signature, and before the last product.
+	single := &SignatureSetOptions{SingleThreaded: true}
+	counting := newCountingContext(0)
+	if valid, err := SignatureSetVerifyContext(counting, pubs, msgs, sigs, single); err != nil || !valid {
+		t.Fatalf("expected set to be valid, got: %v", err)
+	}
+	total := counting.count()
+	pairingChecks := (n + 1 + cancellablePairs - 1) / cancellablePairs
+	for cancelAt := total - pairingChecks + 1; cancelAt <= total; cancelAt++ {
+		counting := newCountingContext(cancelAt)
+		if _, err := SignatureSetVerifyContext(counting, pubs, msgs, sigs, single); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected cancellation at check %d of %d, got: %v", cancelAt, total, err)
+		}
+		// the pairing work stops at the first check after the cancellation
+		if calls := counting.count(); calls != cancelAt {
+			t.Fatalf("expected the pairings to stop at check %d, got %d checks", cancelAt, calls)
+		}
+	}
+
+	// a deadline during the work, with a single-threaded batch that takes much longer than the deadline
+	largePubs, largeMsgs, largeSigs := prepareSignatureSetTest(t, 64)
+	start := time.Now()
+	if valid, err := SignatureSetVerifyContext(context.Background(), largePubs, largeMsgs, largeSigs, &SignatureSetOptions{SingleThreaded: true}); err != nil || !valid {
+		t.Fatalf("expected large set to be valid, got: %v", err)
+	}
+	full := time.Since(start)
+	timeout, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err := SignatureSetVerifyContext(timeout, largePubs, largeMsgs, largeSigs, &SignatureSetOptions{SingleThreaded: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, full verification takes %s, got: %v", full, err)
+	}
+	t.Logf("stopped after %s, full verification takes %s", time.Since(start), full)
+
+	// all workers have stopped
+	if after := runtime.NumGoroutine(); after > goroutines {
+		t.Fatalf("leaked goroutines: %d before, %d after", goroutines, after)
+	}
+}
+
+func TestSignatureSetVerifyDetailed(t *testing.T) {
+	cases := []struct {
+		n       int
+		invalid []int
+	}{
+		{1, nil},
+		{1, []int{0}},
+		{2, []int{1}},
+		{5, []int{0, 4}},
+		{10, nil},
+		{10, []int{3}},
+		{16, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+		{33, []int{7, 8, 20, 32}},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("n_%d_invalid_%v", c.n, c.invalid), func(t *testing.T) {
+			pubs, msgs, sigs := prepareSignatureSetTest(t, c.n)
+			for _, i := range c.invalid {
+				// a valid signature, but of another message
+				other := make([]byte, len(msgs[i]))
+				copy(other, msgs[i])
+				other[0] ^= 1
+				msgs[i] = other
+			}
+			invalid, err := SignatureSetVerifyDetailed(pubs, msgs, sigs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(invalid) != fmt.Sprint(c.invalid) {
+				t.Fatalf("expected invalid indices %v, got %v", c.invalid, invalid)
+			}
+			valid, err := SignatureSetVerify(pubs, msgs, sigs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != (len(invalid) == 0) {
+				t.Fatalf("SignatureSetVerify result %v does not match detailed result", valid)
+			}
+		})
+	}
+
+	// a valid signature of the same message, by another key
+	pubs, msgs, sigs := prepareSignatureSetTest(t, 4)
+	msgs[2] = msgs[1]
+	if invalid, err := SignatureSetVerifyDetailed(pubs, msgs, sigs); err != nil || fmt.Sprint(invalid) != "[2]" {
+		t.Fatalf("expected invalid index 2, got %v, err: %v", invalid, err)
+	}
+
+	if invalid, err := SignatureSetVerifyDetailed(nil, nil, nil); err != nil || invalid != nil {
+		t.Fatalf("expected empty set to be valid, got %v, err: %v", invalid, err)
+	}
+	if _, err := SignatureSetVerifyDetailed(pubs, msgs[:3], sigs); !errors.Is(err, ErrLengthMismatch) {
+		t.Fatalf("expected length mismatch, got: %v", err)
+	}
+
+	var set SignatureSet
+	set.Add(pubs[0], msgs[0], sigs[0])
+	set.Add(pubs[1], msgs[1], sigs[0])
+	if invalid, err := set.VerifyDetailed(); err != nil || fmt.Sprint(invalid) != "[1]" {
+		t.Fatalf("expected invalid index 1, got %v, err: %v", invalid, err)
+	}
+}
+
 type batchVerifyTestCase struct {
 	Input struct {
 		Pubkey    []hexStr48 `json:"pubkeys"`
@@ -119,3 +444,68 @@ func TestSignatureSetVerify(t *testing.T) {
 		}
 	})
 }
+
+func TestSignatureSetVerifyInvalidTuple(t *testing.T) {
+	// Every tuple must be checked, including the tuples next to the first tuple,
+	// which is the only one without a random scalar.
+	for _, n := range []int{8, 16} {
+		pubs, msgs, sigs := prepareSignatureSetTest(t, n)
+		for i := 0; i < n; i++ {
+			t.Run(fmt.Sprintf("n_%d_invalid_%d", n, i), func(t *testing.T) {
+				invalidSigs := append([]*Signature(nil), sigs...)
+				// a valid signature, but of another message
+				invalidSigs[i] = sigs[(i+1)%n]
+				valid, err := SignatureSetVerify(pubs, msgs, invalidSigs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if valid {
+					t.Fatalf("expected set with invalid tuple %d to be invalid", i)
+				}
+			})
+		}
+	}
+}
+
+func TestSignatureSetVerifyIdentity(t *testing.T) {
+	var identityPub Pubkey
+	identityPubRaw := [48]byte{0xc0}
+	if err := identityPub.Deserialize(&identityPubRaw); err != nil {
+		t.Fatal(err)
+	}
+	var identitySig Signature
+	identitySigRaw := [96]byte{0xc0}
+	if err := identitySig.Deserialize(&identitySigRaw); err != nil {
+		t.Fatal(err)
+	}
+
+	// the identity signature of the identity pubkey pairs to one, and would pass the batch equation
+	pubs, msgs, sigs := prepareSignatureSetTest(t, 4)
+	pubs[2], sigs[2] = &identityPub, &identitySig
+	valid, err := SignatureSetVerify(pubs, msgs, sigs)
+	if valid || err != nil {
+		t.Fatalf("expected set with identity tuple to be invalid, without error: %v", err)
+	}
+	if invalid, err := SignatureSetVerifyDetailed(pubs, msgs, sigs); err != nil || fmt.Sprint(invalid) != "[2]" {
+		t.Fatalf("expected invalid index 2, got %v, err: %v", invalid, err)
+	}
+
+	// an identity signature with a valid pubkey, and an invalid tuple in the batch
+	pubs, msgs, sigs = prepareSignatureSetTest(t, 5)
+	sigs[1] = &identitySig
+	sigs[3] = sigs[4]
+	valid, err = SignatureSetVerify(pubs, msgs, sigs)
+	if valid || err != nil {
+		t.Fatalf("expected set with identity signature to be invalid, without error: %v", err)
+	}
+	if invalid, err := SignatureSetVerifyDetailed(pubs, msgs, sigs); err != nil || fmt.Sprint(invalid) != "[1 3]" {
+		t.Fatalf("expected invalid indices [1 3], got %v, err: %v", invalid, err)
+	}
+
+	// nothing left to batch
+	pubs = []*Pubkey{&identityPub, pubs[0]}
+	sigs = []*Signature{&identitySig, &identitySig}
+	if invalid, err := SignatureSetVerifyDetailed(pubs, msgs[:2], sigs); err != nil || fmt.Sprint(invalid) != "[0 1]" {
+		t.Fatalf("expected invalid indices [0 1], got %v, err: %v", invalid, err)
+	}
+}
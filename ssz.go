@@ -0,0 +1,112 @@
+package blsu
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// SSZ encoding of the key types: a Pubkey is a Bytes48, and a Signature is a Bytes96, of the compressed points.
+// Only the encoding and the HashTreeRoot are implemented, without depending on an SSZ library.
+//
+// Pubkey and Signature implement the fastssz Marshaler and Unmarshaler interfaces
+// (SizeSSZ, MarshalSSZ, MarshalSSZTo and UnmarshalSSZ), but not its HashRoot interface:
+// HashTreeRootWith takes a *ssz.Hasher of fastssz, which would make fastssz a dependency of every user of this package.
+// Containers hash a key field with the serialized bytes instead, e.g. hh.PutBytes(raw[:]) in fastssz,
+// or use its root from HashTreeRoot, PubkeyRoot or SignatureRoot.
+
+const (
+	PubkeySSZSize    = 48
+	SignatureSSZSize = 96
+)
+
+// SizeSSZ returns the size of the SSZ encoding, a Bytes48.
+func (pub *Pubkey) SizeSSZ() int {
+	return PubkeySSZSize
+}
+
+// MarshalSSZ returns the SSZ encoding, the compressed point.
+func (pub *Pubkey) MarshalSSZ() ([]byte, error) {
+	out := pub.Serialize()
+	return out[:], nil
+}
+
+// MarshalSSZTo appends the SSZ encoding to dst.
+func (pub *Pubkey) MarshalSSZTo(dst []byte) ([]byte, error) {
+	out := pub.Serialize()
+	return append(dst, out[:]...), nil
+}
+
+// UnmarshalSSZ deserializes the SSZ encoding like Deserialize.
+func (pub *Pubkey) UnmarshalSSZ(buf []byte) error {
+	if len(buf) != PubkeySSZSize {
+		return fmt.Errorf("pubkey SSZ must be %d bytes, got %d", PubkeySSZSize, len(buf))
+	}
+	return pub.Deserialize((*[48]byte)(buf))
+}
+
+// HashTreeRoot returns the SSZ hash_tree_root of the Bytes48:
+// the merkle root of the 2 chunks of 32 bytes, the last one zero-padded.
+// The error is always nil, and only part of the signature for compatibility with SSZ interfaces.
+//
+// The point is compressed on every call, which costs more than the hashing itself.
+// Use PubkeyRoot when the serialized pubkey is already available, e.g. in a container of raw bytes.
+func (pub *Pubkey) HashTreeRoot() ([32]byte, error) {
+	raw := pub.Serialize()
+	return PubkeyRoot(&raw), nil
+}
+
+// PubkeyRoot returns the SSZ hash_tree_root of a serialized pubkey, a Bytes48,
+// without deserializing it: the pubkey is not validated. It does not allocate.
+func PubkeyRoot(raw *[48]byte) [32]byte {
+	var chunks [64]byte
+	copy(chunks[:], raw[:])
+	return sha256.Sum256(chunks[:])
+}
+
+// SizeSSZ returns the size of the SSZ encoding, a Bytes96.
+func (sig *Signature) SizeSSZ() int {
+	return SignatureSSZSize
+}
+
+// MarshalSSZ returns the SSZ encoding, the compressed point.
+func (sig *Signature) MarshalSSZ() ([]byte, error) {
+	out := sig.Serialize()
+	return out[:], nil
+}
+
+// MarshalSSZTo appends the SSZ encoding to dst.
+func (sig *Signature) MarshalSSZTo(dst []byte) ([]byte, error) {
+	out := sig.Serialize()
+	return append(dst, out[:]...), nil
+}
+
+// UnmarshalSSZ deserializes the SSZ encoding like Deserialize.
+func (sig *Signature) UnmarshalSSZ(buf []byte) error {
+	if len(buf) != SignatureSSZSize {
+		return fmt.Errorf("signature SSZ must be %d bytes, got %d", SignatureSSZSize, len(buf))
+	}
+	return sig.Deserialize((*[96]byte)(buf))
+}
+
+// HashTreeRoot returns the SSZ hash_tree_root of the Bytes96:
+// the merkle root of the 3 chunks of 32 bytes, padded to 4 chunks.
+// The error is always nil, and only part of the signature for compatibility with SSZ interfaces.
+//
+// The point is compressed on every call, see PubkeyRoot: use SignatureRoot for a serialized signature.
+func (sig *Signature) HashTreeRoot() ([32]byte, error) {
+	raw := sig.Serialize()
+	return SignatureRoot(&raw), nil
+}
+
+// SignatureRoot returns the SSZ hash_tree_root of a serialized signature, a Bytes96,
+// without deserializing it: the signature is not validated. It does not allocate.
+func SignatureRoot(raw *[96]byte) [32]byte {
+	var pair [64]byte
+	left := sha256.Sum256(raw[:64])
+	copy(pair[:32], raw[64:])
+	// the right half is the 3rd chunk, and a zero chunk
+	right := sha256.Sum256(pair[:])
+	copy(pair[:32], left[:])
+	copy(pair[32:], right[:])
+	return sha256.Sum256(pair[:])
+}
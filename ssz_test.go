@@ -0,0 +1,143 @@
+package blsu
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// merkleize computes the SSZ merkle root of the data, zero-padded to a power-of-two number of 32 byte chunks.
+func merkleize(data []byte) [32]byte {
+	n := 1
+	for n*32 < len(data) {
+		n *= 2
+	}
+	layer := make([][32]byte, n, n)
+	for i := range layer {
+		copy(layer[i][:], data[min(i*32, len(data)):min((i+1)*32, len(data))])
+	}
+	for len(layer) > 1 {
+		next := make([][32]byte, len(layer)/2, len(layer)/2)
+		for i := range next {
+			next[i] = sha256.Sum256(append(layer[2*i][:], layer[2*i+1][:]...))
+		}
+		layer = next
+	}
+	return layer[0]
+}
+
+func TestPubkeySSZ(t *testing.T) {
+	pubkeys, _, _ := prepareSignatureSetTest(t, 1)
+	pub := pubkeys[0]
+	raw := pub.Serialize()
+	if pub.SizeSSZ() != 48 {
+		t.Fatalf("unexpected size: %d", pub.SizeSSZ())
+	}
+	enc, err := pub.MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, raw[:]) {
+		t.Fatal("unexpected SSZ encoding")
+	}
+	prefix := []byte{1, 2, 3}
+	out, err := pub.MarshalSSZTo(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out[:3], prefix) || !bytes.Equal(out[3:], raw[:]) {
+		t.Fatal("unexpected appended SSZ encoding")
+	}
+	var decoded Pubkey
+	if err := decoded.UnmarshalSSZ(enc); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Serialize() != raw {
+		t.Fatal("SSZ roundtrip failed")
+	}
+	if err := decoded.UnmarshalSSZ(enc[1:]); err == nil {
+		t.Fatal("expected short SSZ to fail")
+	}
+	root, err := pub.HashTreeRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != merkleize(raw[:]) {
+		t.Fatalf("unexpected root: %x", root)
+	}
+	if PubkeyRoot(&raw) != root {
+		t.Fatal("expected PubkeyRoot to match HashTreeRoot")
+	}
+	// the raw root does not validate the pubkey
+	var invalid [48]byte
+	invalid[47] = 1
+	if PubkeyRoot(&invalid) != merkleize(invalid[:]) {
+		t.Fatal("unexpected root of invalid pubkey bytes")
+	}
+}
+
+func TestSignatureSSZ(t *testing.T) {
+	_, _, signatures := prepareSignatureSetTest(t, 1)
+	sig := signatures[0]
+	raw := sig.Serialize()
+	if sig.SizeSSZ() != 96 {
+		t.Fatalf("unexpected size: %d", sig.SizeSSZ())
+	}
+	enc, err := sig.MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, raw[:]) {
+		t.Fatal("unexpected SSZ encoding")
+	}
+	var decoded Signature
+	if err := decoded.UnmarshalSSZ(enc); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Serialize() != raw {
+		t.Fatal("SSZ roundtrip failed")
+	}
+	if err := decoded.UnmarshalSSZ(append(enc, 0)); err == nil {
+		t.Fatal("expected long SSZ to fail")
+	}
+	root, err := sig.HashTreeRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != merkleize(raw[:]) {
+		t.Fatalf("unexpected root: %x", root)
+	}
+	if SignatureRoot(&raw) != root {
+		t.Fatal("expected SignatureRoot to match HashTreeRoot")
+	}
+
+	// G2_POINT_AT_INFINITY
+	var infinity [96]byte
+	infinity[0] = 0xc0
+	if err := decoded.Deserialize(&infinity); err != nil {
+		t.Fatal(err)
+	}
+	root, _ = decoded.HashTreeRoot()
+	if root != merkleize(infinity[:]) {
+		t.Fatalf("unexpected infinity root: %x", root)
+	}
+}
+
+func BenchmarkPubkeyHashTreeRoot(b *testing.B) {
+	pubkeys, _, _ := prepareSignatureSetTest(b, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = pubkeys[0].HashTreeRoot()
+	}
+}
+
+func BenchmarkPubkeyRoot(b *testing.B) {
+	pubkeys, _, _ := prepareSignatureSetTest(b, 1)
+	raw := pubkeys[0].Serialize()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = PubkeyRoot(&raw)
+	}
+}
@@ -0,0 +1,156 @@
+package blsu
+
+import (
+	"errors"
+	"fmt"
+	kbls "github.com/kilic/bls12-381"
+	"math/big"
+)
+
+// Uncompressed points encode both the x and y coordinates, and avoid the square root of decompression.
+// The 3 most significant bits are flags, like the compressed encoding:
+// the compression flag and sort flag must be zero, and the infinity flag is set only for the identity point,
+// which is encoded as 0x40 followed by zero bytes.
+
+// checkUncompressedFlags validates the flag bits of an uncompressed point encoding,
+// and returns true if it is the encoding of the identity point.
+func checkUncompressedFlags(in []byte) (infinity bool, err error) {
+	if in[0]&(1<<7) != 0 {
+		return false, errors.New("compression flag must be zero")
+	}
+	if in[0]&(1<<5) != 0 {
+		return false, errors.New("sort flag must be zero")
+	}
+	if in[0]&(1<<6) != 0 {
+		if in[0] != 0x40 {
+			return false, errors.New("unexpected bits with infinity flag")
+		}
+		for i := 1; i < len(in); i++ {
+			if in[i] != 0 {
+				return false, fmt.Errorf("non-zero byte %d with infinity flag", i)
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// decodePubkeyUncompressed decodes an uncompressed G1 point. The identity point is allowed.
+// The subgroup check is skipped if trusted.
+func decodePubkeyUncompressed(in *[96]byte, trusted bool) (*kbls.PointG1, error) {
+	g1 := kbls.NewG1()
+	infinity, err := checkUncompressedFlags(in[:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPubkeyEncoding, err)
+	}
+	if infinity {
+		return g1.Zero(), nil
+	}
+	p, err := g1.FromBytes(in[:])
+	if err != nil {
+		// The kilic errors are not typed: the reason is only computed on failure, to keep valid points fast.
+		// Tell a non-canonical encoding apart from a point that is not on the curve.
+		x := new(big.Int).SetBytes(in[:48])
+		y := new(big.Int).SetBytes(in[48:])
+		if x.Cmp(fpModulus) >= 0 || y.Cmp(fpModulus) >= 0 {
+			return nil, fmt.Errorf("%w: coordinate is not smaller than the field modulus", ErrPubkeyEncoding)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPubkeyNotOnCurve, err)
+	}
+	// (0, 0) is not on the curve, but decoded as identity point by kilic FromBytes
+	if g1.IsZero(p) {
+		return nil, fmt.Errorf("%w: (0, 0) without infinity flag", ErrPubkeyNotOnCurve)
+	}
+	if !trusted && !g1.InCorrectSubgroup(p) {
+		return nil, ErrPubkeyNotInSubgroup
+	}
+	return p, nil
+}
+
+// SerializeUncompressed serializes to an uncompressed point, the x and y coordinates.
+func (pub *Pubkey) SerializeUncompressed() (out [96]byte) {
+	copy(out[:], kbls.NewG1().ToUncompressed((*kbls.PointG1)(pub)))
+	return
+}
+
+// DeserializeUncompressed deserializes an uncompressed point,
+// with the same validation as Deserialize: the identity pubkey is allowed.
+func (pub *Pubkey) DeserializeUncompressed(in *[96]byte) error {
+	p, err := decodePubkeyUncompressed(in, false)
+	if err != nil {
+		return err
+	}
+	*pub = (Pubkey)(*p)
+	return nil
+}
+
+// DeserializeUncompressedTrusted deserializes an uncompressed point, without the subgroup check.
+// Only use this for trusted data that was validated before serialization, e.g. a local cache of pubkeys.
+// The encoding and curve equation are still checked.
+func (pub *Pubkey) DeserializeUncompressedTrusted(in *[96]byte) error {
+	p, err := decodePubkeyUncompressed(in, true)
+	if err != nil {
+		return err
+	}
+	*pub = (Pubkey)(*p)
+	return nil
+}
+
+// decodeSignatureUncompressed decodes an uncompressed G2 point. The identity point is allowed.
+// The subgroup check is skipped if trusted.
+func decodeSignatureUncompressed(in *[192]byte, trusted bool) (*kbls.PointG2, error) {
+	g2 := kbls.NewG2()
+	infinity, err := checkUncompressedFlags(in[:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSignatureEncoding, err)
+	}
+	if infinity {
+		return g2.Zero(), nil
+	}
+	p, err := g2.FromBytes(in[:])
+	if err != nil {
+		// see decodePubkeyUncompressed
+		for i := 0; i < 192; i += 48 {
+			if new(big.Int).SetBytes(in[i:i+48]).Cmp(fpModulus) >= 0 {
+				return nil, fmt.Errorf("%w: coordinate is not smaller than the field modulus", ErrSignatureEncoding)
+			}
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSignatureNotOnCurve, err)
+	}
+	// (0, 0) is not on the curve, but decoded as identity point by kilic FromBytes
+	if g2.IsZero(p) {
+		return nil, fmt.Errorf("%w: (0, 0) without infinity flag", ErrSignatureNotOnCurve)
+	}
+	if !trusted && !g2.InCorrectSubgroup(p) {
+		return nil, ErrSignatureNotInSubgroup
+	}
+	return p, nil
+}
+
+// SerializeUncompressed serializes to an uncompressed point, the x and y coordinates.
+func (sig *Signature) SerializeUncompressed() (out [192]byte) {
+	copy(out[:], kbls.NewG2().ToUncompressed((*kbls.PointG2)(sig)))
+	return
+}
+
+// DeserializeUncompressed deserializes an uncompressed point, with the same validation as Deserialize.
+func (sig *Signature) DeserializeUncompressed(in *[192]byte) error {
+	p, err := decodeSignatureUncompressed(in, false)
+	if err != nil {
+		return err
+	}
+	*sig = (Signature)(*p)
+	return nil
+}
+
+// DeserializeUncompressedTrusted deserializes an uncompressed point, without the subgroup check.
+// Only use this for trusted data that was validated before serialization, e.g. a local cache of signatures.
+// The encoding and curve equation are still checked.
+func (sig *Signature) DeserializeUncompressedTrusted(in *[192]byte) error {
+	p, err := decodeSignatureUncompressed(in, true)
+	if err != nil {
+		return err
+	}
+	*sig = (Signature)(*p)
+	return nil
+}
@@ -0,0 +1,175 @@
+package blsu
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPubkeyUncompressed(t *testing.T) {
+	pubkeys, _, _ := prepareSignatureSetTest(t, 1)
+	valid := pubkeys[0].SerializeUncompressed()
+	var identity [96]byte
+	identity[0] = 0x40
+
+	// a point on the curve, outside of the G1 subgroup
+	x := findX(0, true)
+	rhs := new(big.Int).Exp(x, big.NewInt(3), fpModulus)
+	rhs.Add(rhs, big.NewInt(4))
+	y := new(big.Int).ModSqrt(rhs, fpModulus)
+	var nonSubgroup [96]byte
+	x.FillBytes(nonSubgroup[:48])
+	y.FillBytes(nonSubgroup[48:])
+
+	offCurve := valid
+	offCurve[95] ^= 1
+	withCompression := valid
+	withCompression[0] |= 0x80
+	withSort := valid
+	withSort[0] |= 0x20
+	withInfinity := valid
+	withInfinity[0] |= 0x40
+	infinityGarbage := identity
+	infinityGarbage[60] = 1
+	var xTooLarge [96]byte
+	fpModulus.FillBytes(xTooLarge[:48])
+	copy(xTooLarge[48:], valid[48:])
+
+	cases := []struct {
+		name       string
+		in         [96]byte
+		err        error
+		trustedErr error
+	}{
+		{"valid", valid, nil, nil},
+		{"identity", identity, nil, nil},
+		{"not in subgroup", nonSubgroup, ErrPubkeyNotInSubgroup, nil},
+		{"not on curve", offCurve, ErrPubkeyNotOnCurve, ErrPubkeyNotOnCurve},
+		{"zero bytes", [96]byte{}, ErrPubkeyNotOnCurve, ErrPubkeyNotOnCurve},
+		{"compression flag", withCompression, ErrPubkeyEncoding, ErrPubkeyEncoding},
+		{"sort flag", withSort, ErrPubkeyEncoding, ErrPubkeyEncoding},
+		{"infinity flag on pubkey", withInfinity, ErrPubkeyEncoding, ErrPubkeyEncoding},
+		{"infinity with non-zero byte", infinityGarbage, ErrPubkeyEncoding, ErrPubkeyEncoding},
+		{"x = p", xTooLarge, ErrPubkeyEncoding, ErrPubkeyEncoding},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var pub Pubkey
+			err := pub.DeserializeUncompressed(&c.in)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, got: %v", c.err, err)
+			}
+			if err == nil && pub.SerializeUncompressed() != c.in {
+				t.Fatal("expected canonical uncompressed encoding")
+			}
+			var trusted Pubkey
+			if err := trusted.DeserializeUncompressedTrusted(&c.in); !errors.Is(err, c.trustedErr) {
+				t.Fatalf("expected trusted error %v, got: %v", c.trustedErr, err)
+			}
+		})
+	}
+
+	// the uncompressed encoding decodes to the same pubkey as the compressed encoding
+	var pub Pubkey
+	if err := pub.DeserializeUncompressed(&valid); err != nil {
+		t.Fatal(err)
+	}
+	if pub.Serialize() != pubkeys[0].Serialize() {
+		t.Fatal("uncompressed pubkey differs from compressed pubkey")
+	}
+}
+
+func TestSignatureUncompressed(t *testing.T) {
+	_, _, signatures := prepareSignatureSetTest(t, 1)
+	valid := signatures[0].SerializeUncompressed()
+	var identity [192]byte
+	identity[0] = 0x40
+
+	// the point with x = 2, on the curve, outside of the G2 subgroup
+	var nonSubgroup [192]byte
+	nonSubgroupHex := "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000" + // x.c1
+		"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002" + // x.c0
+		"172e93db764a8400a7d5071b6b6f5de0da2f0f4a063119abca014006b7c40a2cfe291a1924e65db0d6d0fcfbf3bf3d5c" + // y.c1
+		"18c6b864ae17dc9da64203ffefb966306425a7bc6aeb7c75247438372716284a4173830420cd476ba1a365b95bfcec38" // y.c0
+	if _, err := hex.Decode(nonSubgroup[:], []byte(nonSubgroupHex)); err != nil {
+		t.Fatal(err)
+	}
+
+	offCurve := valid
+	offCurve[191] ^= 1
+	withCompression := valid
+	withCompression[0] |= 0x80
+	withSort := valid
+	withSort[0] |= 0x20
+	withInfinity := valid
+	withInfinity[0] |= 0x40
+	infinityGarbage := identity
+	infinityGarbage[100] = 1
+	yTooLarge := valid
+	fpModulus.FillBytes(yTooLarge[144:])
+
+	cases := []struct {
+		name       string
+		in         [192]byte
+		err        error
+		trustedErr error
+	}{
+		{"valid", valid, nil, nil},
+		{"identity", identity, nil, nil},
+		{"not in subgroup", nonSubgroup, ErrSignatureNotInSubgroup, nil},
+		{"not on curve", offCurve, ErrSignatureNotOnCurve, ErrSignatureNotOnCurve},
+		{"zero bytes", [192]byte{}, ErrSignatureNotOnCurve, ErrSignatureNotOnCurve},
+		{"compression flag", withCompression, ErrSignatureEncoding, ErrSignatureEncoding},
+		{"sort flag", withSort, ErrSignatureEncoding, ErrSignatureEncoding},
+		{"infinity flag on signature", withInfinity, ErrSignatureEncoding, ErrSignatureEncoding},
+		{"infinity with non-zero byte", infinityGarbage, ErrSignatureEncoding, ErrSignatureEncoding},
+		{"y.c0 = p", yTooLarge, ErrSignatureEncoding, ErrSignatureEncoding},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var sig Signature
+			err := sig.DeserializeUncompressed(&c.in)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, got: %v", c.err, err)
+			}
+			if err == nil && sig.SerializeUncompressed() != c.in {
+				t.Fatal("expected canonical uncompressed encoding")
+			}
+			var trusted Signature
+			if err := trusted.DeserializeUncompressedTrusted(&c.in); !errors.Is(err, c.trustedErr) {
+				t.Fatalf("expected trusted error %v, got: %v", c.trustedErr, err)
+			}
+		})
+	}
+
+	var sig Signature
+	if err := sig.DeserializeUncompressedTrusted(&valid); err != nil {
+		t.Fatal(err)
+	}
+	if sig.Serialize() != signatures[0].Serialize() {
+		t.Fatal("uncompressed signature differs from compressed signature")
+	}
+}
+
+func BenchmarkPubkeyDeserialize(b *testing.B) {
+	pubkeys, _, _ := prepareSignatureSetTest(b, 1)
+	compressed := pubkeys[0].Serialize()
+	uncompressed := pubkeys[0].SerializeUncompressed()
+	var pub Pubkey
+	b.Run("compressed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = pub.Deserialize(&compressed)
+		}
+	})
+	b.Run("uncompressed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = pub.DeserializeUncompressed(&uncompressed)
+		}
+	})
+	b.Run("uncompressed trusted", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = pub.DeserializeUncompressedTrusted(&uncompressed)
+		}
+	})
+}